@@ -0,0 +1,110 @@
+// Based on Plan9 cat
+
+package cat
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/c0defellas/enzo/internal/cli"
+)
+
+// writeError is a failure to write the output, which ends cat.
+type writeError struct {
+	err error
+}
+
+func (e writeError) Error() string {
+	if pathErr, ok := e.err.(*os.PathError); ok {
+		return "write error: " + pathErr.Err.Error()
+	}
+	return "write error: " + e.err.Error()
+}
+
+func (e writeError) Unwrap() error {
+	return e.err
+}
+
+func cat(in io.Reader, out io.Writer, name string) error {
+	var (
+		buf [8192]byte
+	)
+
+	for {
+		n, err := in.Read(buf[:])
+
+		// what was read goes out before any error, EOF included
+		if n > 0 {
+			b := buf[:n]
+
+			if written, werr := out.Write(b); written != len(b) {
+				if werr == nil {
+					werr = io.ErrShortWrite
+				}
+				return writeError{werr}
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*os.PathError); !ok {
+				err = &os.PathError{Op: "read", Path: name, Err: err}
+			}
+			return err
+		}
+	}
+}
+
+// runcat copies files to out, going on with the next file when one
+// can't be read. It returns the errors of all of them joined.
+func runcat(files []string, out io.Writer) error {
+	var errs []error
+	for _, fname := range files {
+		f, err := os.Open(fname)
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = cat(f, out, fname)
+		f.Close()
+		if _, ok := err.(writeError); ok {
+			return err
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run runs cat with args, args[0] being the command name, returning
+// the exit status. A file that can't be read is reported and skipped,
+// for status 1. env may turn on verbose diagnostics with ENZO_DEBUG.
+func Run(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int {
+	name := "cat"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	flags := cli.NewFlagSet(name, "[FILE]...")
+	if err := flags.Parse(args); err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+
+	diag := flags.Diag(stderr, env)
+
+	var err error
+	if files := flags.Args(); len(files) == 0 {
+		err = cat(stdin, stdout, "<stdin>")
+	} else {
+		err = runcat(files, stdout)
+	}
+	if err != nil {
+		diag.Error("", err)
+		return 1
+	}
+	return 0
+}
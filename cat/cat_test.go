@@ -0,0 +1,255 @@
+package cat
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAll(t *testing.T, reader io.Reader) string {
+	content, err := ioutil.ReadAll(reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func writeAll(t *testing.T, w io.Writer, data string) {
+	_, err := w.Write([]byte(data))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Only tests that cat copies the content of in into out
+func testCatInOut(testList []string, t *testing.T) {
+	for _, expected := range testList {
+		t.Run(expected, func(t *testing.T) {
+			var in, out bytes.Buffer
+
+			writeAll(t, &in, expected)
+
+			err := cat(&in, &out, "test")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			got := readAll(t, &out)
+
+			if got != expected {
+				t.Errorf("Expected '%s' but got '%s'", expected, got)
+				return
+			}
+		})
+	}
+}
+
+func TestCatInOut(t *testing.T) {
+	testList := []string{
+		"",
+		"test",
+		"AAAAAAAAAAAAAAAAAAAAAAAAAA",
+		`1
+2
+3
+4
+5
+6
+7
+8
+9
+10
+`,
+	}
+
+	testCatInOut(testList, t)
+}
+
+func writeOnTempfile(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("/tmp", "enzo-test-cat")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeAll(t, f, contents)
+	f.Close()
+	return f.Name()
+}
+
+func testCatFile(expected string, t *testing.T) {
+	filename := writeOnTempfile(t, expected)
+
+	var out bytes.Buffer
+
+	err := runcat([]string{filename}, &out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := readAll(t, &out)
+
+	if string(got) != expected {
+		t.Fatalf("Got %q but expected was %q", string(got), expected)
+	}
+}
+
+func TestHandlesMultipleFiles(t *testing.T) {
+	data1 := "data1"
+	data2 := "data2"
+	filename1 := writeOnTempfile(t, data1)
+	filename2 := writeOnTempfile(t, data2)
+
+	var out bytes.Buffer
+
+	err := runcat([]string{filename1, filename2}, &out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := readAll(t, &out)
+	expected := data1 + data2
+	if string(got) != expected {
+		t.Fatalf("Got %q but expected was %q", string(got), expected)
+	}
+}
+
+func TestHandleOneFile(t *testing.T) {
+	testTbl := []string{
+		"",
+		"1 line",
+		`multi
+		line`,
+		`
+`,
+	}
+
+	for _, test := range testTbl {
+		testCatFile(test, t)
+	}
+}
+
+func TestHandleFileNotFound(t *testing.T) {
+	err := runcat([]string{"/<path-do-not-exists>"}, os.Stdout)
+
+	if err == nil {
+		t.Errorf("Must fail")
+		return
+	}
+}
+
+type fakeIO struct {
+	Err error
+	N   int
+}
+
+func (f fakeIO) Read(p []byte) (n int, err error) {
+	return f.N, f.Err
+}
+
+func (f fakeIO) Write(p []byte) (n int, err error) {
+	return f.N, f.Err
+}
+
+func TestHandleReadError(t *testing.T) {
+	in := fakeIO{N: 1, Err: errors.New("injectedReadError")}
+	var out bytes.Buffer
+	err := cat(in, &out, "readError")
+
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if _, ok := err.(writeError); ok || out.Len() != 1 {
+		t.Fatalf("Expected a read error after the byte read, got %v and %q", err, out.String())
+	}
+
+	// errors coming along with nothing read count too
+	err = cat(fakeIO{Err: errors.New("injectedReadError")}, &out, "readError")
+	if err == nil || err.Error() != "read readError: injectedReadError" {
+		t.Fatalf("Expected the read error, got %v", err)
+	}
+}
+
+func TestHandleWriteError(t *testing.T) {
+	in := fakeIO{N: 2}
+	out := fakeIO{N: 1, Err: errors.New("injectedWriteError")}
+	err := cat(in, out, "writeError")
+
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	status := Run(bytes.NewBufferString("from stdin"), &stdout, &stderr, []string{"cat"}, nil)
+	if status != 0 || stdout.String() != "from stdin" || stderr.Len() != 0 {
+		t.Errorf("status %d, stdout %q, stderr %q", status, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	status = Run(nil, &stdout, &stderr, []string{"cat", "/<path-do-not-exists>"}, nil)
+	if status != 1 || stdout.Len() != 0 {
+		t.Errorf("status %d, stdout %q, stderr %q", status, stdout.String(), stderr.String())
+	}
+	if expected := "cat: /<path-do-not-exists>: no such file or directory\n"; stderr.String() != expected {
+		t.Errorf("expected stderr %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestRunPartialFailure(t *testing.T) {
+	filename := writeOnTempfile(t, "exists")
+	defer os.Remove(filename)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"cat", "/<path-do-not-exists>", filename, "-v"}
+	status := Run(nil, &stdout, &stderr, args, nil)
+	if status != 1 || stdout.String() != "exists" {
+		t.Errorf("status %d, stdout %q", status, stdout.String())
+	}
+	if expected := "cat: /<path-do-not-exists>: no such file or directory (open, ENOENT)\n"; stderr.String() != expected {
+		t.Errorf("expected stderr %q, got %q", expected, stderr.String())
+	}
+
+	stderr.Reset()
+	dir := filepath.Dir(filename)
+	status = Run(nil, &stdout, &stderr, []string{"cat", dir}, nil)
+	if expected := "cat: " + dir + ": is a directory\n"; status != 1 || stderr.String() != expected {
+		t.Errorf("status %d, expected stderr %q, got %q", status, expected, stderr.String())
+	}
+
+	stderr.Reset()
+	status = Run(nil, fakeIO{Err: errors.New("disk full")}, &stderr, []string{"cat", filename}, nil)
+	if expected := "cat: write error: disk full\n"; status != 1 || stderr.String() != expected {
+		t.Errorf("status %d, expected stderr %q, got %q", status, expected, stderr.String())
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if status := Run(nil, &stdout, &stderr, []string{"cat", "-x"}, nil); status != 2 {
+		t.Errorf("expected status 2, got %d", status)
+	}
+	if stdout.Len() != 0 || stderr.Len() == 0 {
+		t.Errorf("expected the usage on stderr, got stdout %q stderr %q", stdout.String(), stderr.String())
+	}
+
+	// after -- a dash starts a file name, not an option
+	stderr.Reset()
+	if status := Run(nil, &stdout, &stderr, []string{"cat", "--", "-x"}, nil); status != 1 {
+		t.Errorf("expected status 1, got %d: %s", status, stderr.String())
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/c0defellas/enzo/echo"
+)
+
+func main() {
+	os.Exit(echo.Run(os.Stdin, os.Stdout, os.Stderr, os.Args, os.Environ()))
+}
@@ -0,0 +1,156 @@
+// enzo is a multicall binary holding every tool, so the Go runtime
+// ships only once. It runs the tool named by argv[0], as a link to it,
+// or by its first argument.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/c0defellas/enzo/cat"
+	"github.com/c0defellas/enzo/echo"
+	"github.com/c0defellas/enzo/internal/cli"
+	"github.com/c0defellas/enzo/kill"
+	"github.com/c0defellas/enzo/ls"
+	"github.com/c0defellas/enzo/uniq"
+)
+
+// tool is the entry point every tool package provides.
+type tool func(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int
+
+var tools = map[string]tool{
+	"cat":  cat.Run,
+	"echo": echo.Run,
+	"kill": kill.Run,
+	"ls":   ls.Run,
+	"uniq": uniq.Run,
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage:
+enzo TOOL [args...]
+enzo --list
+enzo --install [--hard] DIR
+enzo --help | --version`)
+}
+
+// toolName returns the tool called as argv0, without the .exe suffix
+// of windows executables.
+func toolName(argv0 string) string {
+	return strings.TrimSuffix(filepath.Base(argv0), ".exe")
+}
+
+// toolNames returns the names of the tools, sorted.
+func toolNames() []string {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// list writes the names of the tools, one per line.
+func list(out io.Writer) {
+	for _, name := range toolNames() {
+		fmt.Fprintln(out, name)
+	}
+}
+
+// install links every tool in dir to exe, with symbolic links unless
+// hard is set. Entries already leading to exe are left as they are,
+// and nothing is linked when another file is in the way.
+func install(dir, exe string, hard bool) error {
+	exeInfo, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, name := range toolNames() {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err != nil {
+			missing = append(missing, link)
+			continue
+		}
+		if info, err := os.Stat(link); err != nil || !os.SameFile(info, exeInfo) {
+			return errors.New(link + " already exists")
+		}
+	}
+
+	for _, link := range missing {
+		if hard {
+			err = os.Link(exe, link)
+		} else {
+			err = os.Symlink(exe, link)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs tool on the process arguments and exits with its status.
+func run(tool tool, args []string) {
+	os.Exit(tool(os.Stdin, os.Stdout, os.Stderr, args, os.Environ()))
+}
+
+func main() {
+	if tool, ok := tools[toolName(os.Args[0])]; ok {
+		run(tool, os.Args)
+	}
+
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "--help":
+		usage(os.Stdout)
+		return
+	case "--version":
+		fmt.Printf("enzo %s\n", cli.Version)
+		return
+	case "--list":
+		list(os.Stdout)
+		return
+	case "--install":
+		hard := len(args) > 1 && args[1] == "--hard"
+		if hard {
+			args = args[1:]
+		}
+		if len(args) != 2 {
+			usage(os.Stderr)
+			os.Exit(2)
+		}
+
+		exe, err := os.Executable()
+		if err == nil {
+			exe, err = filepath.Abs(exe)
+		}
+		if err == nil {
+			err = install(args[1], exe, hard)
+		}
+		if err != nil {
+			cli.NewFlagSet("enzo").Diag(os.Stderr, os.Environ()).Error("", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	tool, ok := tools[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "enzo: unknown tool %q\n", args[0])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	run(tool, args)
+}
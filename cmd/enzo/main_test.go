@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToolName(t *testing.T) {
+	tests := []struct {
+		argv0    string
+		expected string
+	}{
+		{"ls", "ls"},
+		{"/bin/uniq", "uniq"},
+		{"./enzo", "enzo"},
+		{`cat.exe`, "cat"},
+	}
+
+	for _, test := range tests {
+		if name := toolName(test.argv0); name != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.argv0, test.expected, name)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	var buf bytes.Buffer
+	list(&buf)
+
+	if expected := "cat\necho\nkill\nls\nuniq\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enzo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	exe := filepath.Join(dir, "enzo")
+	if err := ioutil.WriteFile(exe, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	exeInfo, err := os.Stat(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hard := range []bool{false, true} {
+		bin := filepath.Join(dir, "bin")
+		if hard {
+			bin = filepath.Join(dir, "hardbin")
+		}
+		if err := os.Mkdir(bin, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := install(bin, exe, hard); err != nil {
+			t.Fatalf("hard=%v: %v", hard, err)
+		}
+		// Installing again leaves the links in place.
+		if err := install(bin, exe, hard); err != nil {
+			t.Fatalf("hard=%v: reinstalling: %v", hard, err)
+		}
+
+		for name := range tools {
+			info, err := os.Stat(filepath.Join(bin, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !os.SameFile(info, exeInfo) {
+				t.Errorf("hard=%v: %s doesn't lead to enzo", hard, name)
+			}
+
+			linfo, err := os.Lstat(filepath.Join(bin, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := linfo.Mode()&os.ModeSymlink != 0; isLink == hard {
+				t.Errorf("hard=%v: %s has mode %v", hard, name, linfo.Mode())
+			}
+		}
+	}
+
+	other := filepath.Join(dir, "other")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(other, "ls"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := install(other, exe, false); err == nil {
+		t.Error("expected an error installing over an existing file")
+	}
+	// a conflict leaves the directory as it was
+	entries, err := ioutil.ReadDir(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only ls in %s, got %d entries", other, len(entries))
+	}
+
+	dangling := filepath.Join(dir, "dangling")
+	if err := os.Mkdir(dangling, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "nowhere"), filepath.Join(dangling, "uniq")); err != nil {
+		t.Fatal(err)
+	}
+	if err := install(dangling, exe, false); err == nil {
+		t.Error("expected an error installing over a dangling link")
+	}
+	if entries, err := ioutil.ReadDir(dangling); err != nil || len(entries) != 1 {
+		t.Errorf("expected only uniq in %s, got %d entries: %v", dangling, len(entries), err)
+	}
+}
@@ -1,14 +0,0 @@
-// +build windows
-package main
-
-import (
-	"os"
-)
-
-func lookupUser(fileinfo os.FileInfo) (string, error) {
-	return "unknown", nil
-}
-
-func lookupGroup(fileinfo os.FileInfo) (string, error) {
-	return "unknown", nil
-}
\ No newline at end of file
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/c0defellas/enzo/ls"
+)
+
+func main() {
+	os.Exit(ls.Run(os.Stdin, os.Stdout, os.Stderr, os.Args, os.Environ()))
+}
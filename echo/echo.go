@@ -0,0 +1,66 @@
+package echo
+
+import (
+	"io"
+
+	"github.com/c0defellas/enzo/internal/cli"
+)
+
+func echo(out io.Writer, args []string, newline bool) error {
+	var line []byte
+
+	for i := 0; i < len(args); i++ {
+		line = append(line, args[i]...)
+
+		if i < len(args)-1 {
+			line = append(line, ' ')
+		}
+	}
+
+	if newline {
+		line = append(line, 0x0a)
+	}
+
+	_, err := out.Write(line)
+	return err
+}
+
+func newFlagSet(name string, newline *bool) *cli.FlagSet {
+	flags := cli.NewFlagSet(name, "[-n] [STRING]...")
+	flags.BoolFunc('n', "", "do not output the trailing newline", func() { *newline = false })
+	// echo prints anything after its options, even unknown ones
+	flags.StopAtOperand = true
+	flags.UnknownAsOperand = true
+	return flags
+}
+
+func parsearg(args []string) ([]string, bool, *cli.FlagSet, error) {
+	newline := true
+
+	name := "echo"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	flags := newFlagSet(name, &newline)
+	if err := flags.Parse(args); err != nil {
+		return nil, newline, flags, err
+	}
+
+	return flags.Args(), newline, flags, nil
+}
+
+// Run runs echo with args, args[0] being the command name, returning
+// the exit status. stdin is unused, env may turn on verbose
+// diagnostics with ENZO_DEBUG.
+func Run(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int {
+	args, newline, flags, err := parsearg(args)
+	if err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+	if err := echo(stdout, args, newline); err != nil {
+		flags.Diag(stderr, env).Error("write error", err)
+		return 1
+	}
+	return 0
+}
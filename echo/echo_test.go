@@ -0,0 +1,172 @@
+package echo
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+type testEcho struct {
+	args     []string
+	expected string
+	newline  bool
+}
+
+func testecho(args []string, expected string, newline bool, t *testing.T) {
+	var out bytes.Buffer
+
+	echo(&out, args, newline)
+
+	if string(out.Bytes()) != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, string(out.Bytes()))
+		return
+	}
+}
+
+func TestEcho(t *testing.T) {
+	testTbl := []testEcho{
+		{
+			[]string{}, "", false, // echo -n
+		},
+		{
+			[]string{"hello"}, "hello", false, // echo -n hello
+		},
+		{
+			[]string{"hello", "world"}, "hello world", false, // echo -n hello world
+		},
+		{
+			[]string{"hello", "world"}, `hello world
+`, true, // echo hello world
+		},
+		{
+			[]string{`hello
+world`}, `hello
+world`, false},
+	}
+
+	for _, test := range testTbl {
+		testecho(test.args, test.expected, test.newline, t)
+	}
+}
+
+type testArgs struct {
+	args    []string
+	newline bool
+}
+
+func testParseArgs(args []string, expected testArgs, t *testing.T) {
+	parsed, newline, _, err := parsearg(args)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(parsed) != len(expected.args) {
+		t.Errorf("Expect no args")
+		return
+	}
+
+	if newline != expected.newline {
+		t.Errorf("Expected %v newline", expected.newline)
+		return
+	}
+
+	for i := 0; i < len(parsed); i++ {
+		if parsed[i] != expected.args[i] {
+			t.Errorf("Expected '%s' but got '%s'", parsed[i], expected.args[i])
+			return
+		}
+	}
+}
+
+func TestParseArg(t *testing.T) {
+	testTbl := []struct {
+		args     []string
+		expected testArgs
+	}{
+		{
+			[]string{},
+			testArgs{[]string{}, true},
+		},
+		{
+			[]string{"echo"},
+			testArgs{[]string{}, true},
+		},
+		{
+			[]string{"echo", "-n"},
+			testArgs{[]string{}, false},
+		},
+		{
+			[]string{"echo", "-n", "hello"},
+			testArgs{[]string{"hello"}, false},
+		},
+		{
+			[]string{"echo", "-nn", "hello"},
+			testArgs{[]string{"hello"}, false},
+		},
+		{
+			[]string{"echo", "-x", "-n"},
+			testArgs{[]string{"-x", "-n"}, true},
+		},
+		{
+			[]string{"echo", "hello", "-n"},
+			testArgs{[]string{"hello", "-n"}, true},
+		},
+		{
+			[]string{"echo", "--", "foo"},
+			testArgs{[]string{"--", "foo"}, true},
+		},
+		{
+			[]string{"echo", "-n", "--", "-n"},
+			testArgs{[]string{"--", "-n"}, false},
+		},
+		{
+			[]string{"echo", "--verbose", "hi"},
+			testArgs{[]string{"--verbose", "hi"}, true},
+		},
+	}
+
+	for _, test := range testTbl {
+		testParseArgs(test.args, test.expected, t)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+
+	if status := Run(nil, &out, nil, []string{"echo", "-n", "hello", "world"}, nil); status != 0 {
+		t.Errorf("Expected status 0 but got %d", status)
+	}
+	if out.String() != "hello world" {
+		t.Errorf("Expected 'hello world' but got '%s'", out.String())
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if status := Run(nil, &stdout, &stderr, []string{"echo", "--help"}, nil); status != 0 {
+		t.Errorf("Expected status 0 but got %d", status)
+	}
+	if !strings.HasPrefix(stdout.String(), "Usage: echo [-n] [STRING]...") {
+		t.Errorf("Expected the usage but got '%s'", stdout.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestRunWriteError(t *testing.T) {
+	var stderr bytes.Buffer
+
+	if status := Run(nil, failingWriter{}, &stderr, []string{"echo", "hi"}, []string{"ENZO_DEBUG=1"}); status != 1 {
+		t.Errorf("Expected status 1 but got %d", status)
+	}
+	if expected := "echo: write error: broken pipe (EPIPE)\n"; stderr.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, stderr.String())
+	}
+}
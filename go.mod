@@ -0,0 +1,3 @@
+module github.com/c0defellas/enzo
+
+go 1.20
@@ -0,0 +1,418 @@
+// Package cli parses the command lines of every tool the same way:
+// combined short options (-la), long options (--all, --width=5 or
+// --width 5), -- to end the options, and --help, --version and
+// -v/--verbose for verbose diagnostics.
+//
+// The letters after a single dash are short options. A command may let
+// some long option names follow a single dash too, as the flag package
+// read them, so kill -safe and uniq -top 10 keep working.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Version is reported by --version, set at link time with
+// -ldflags "-X github.com/c0defellas/enzo/internal/cli.Version=...".
+var Version = "dev"
+
+var (
+	// ErrHelp is returned by Parse when --help is given.
+	ErrHelp = errors.New("help requested")
+	// ErrVersion is returned by Parse when --version is given.
+	ErrVersion = errors.New("version requested")
+)
+
+type option struct {
+	short  rune
+	long   string
+	arg    string
+	help   string
+	isBool bool
+	// optional values come only after =, def being used without one
+	optional bool
+	def      string
+	set      func(string) error
+}
+
+// name returns how the option is written in messages.
+func (o *option) name() string {
+	if o.long != "" {
+		return "--" + o.long
+	}
+	return "-" + string(o.short)
+}
+
+// FlagSet is the set of options of a command.
+type FlagSet struct {
+	name     string
+	synopsis []string
+	options  []*option
+	args     []string
+	verbose  bool
+	// singleDash are the long names also taken after a single dash
+	singleDash map[string]bool
+
+	// StopAtOperand ends the options at the first operand instead of
+	// taking options anywhere on the command line.
+	StopAtOperand bool
+	// UnknownAsOperand takes an unknown option, or --, as the first
+	// operand, as echo does.
+	UnknownAsOperand bool
+}
+
+// NewFlagSet returns the options of the command name, which may be a
+// path such as args[0], of which only the last element is kept. synopsis
+// holds the usage lines printed after the name, such as
+// "[OPTION]... [FILE]...".
+func NewFlagSet(name string, synopsis ...string) *FlagSet {
+	return &FlagSet{name: filepath.Base(name), synopsis: synopsis}
+}
+
+// Name returns the name of the command.
+func (f *FlagSet) Name() string {
+	return f.name
+}
+
+func (f *FlagSet) add(o *option) {
+	f.options = append(f.options, o)
+}
+
+// Bool defines an option without value setting *p. A short of 0 or an
+// empty long leave out that form.
+func (f *FlagSet) Bool(p *bool, short rune, long, help string) {
+	f.add(&option{short: short, long: long, help: help, isBool: true, set: func(val string) error {
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.New("expects true or false")
+		}
+		*p = v
+		return nil
+	}})
+}
+
+// BoolFunc defines an option without value calling set.
+func (f *FlagSet) BoolFunc(short rune, long, help string, set func()) {
+	f.add(&option{short: short, long: long, help: help, isBool: true, set: func(val string) error {
+		if val != "true" {
+			return errors.New("takes no value")
+		}
+		set()
+		return nil
+	}})
+}
+
+// String defines an option with a value named arg in the usage.
+func (f *FlagSet) String(p *string, short rune, long, arg, help string) {
+	f.Func(short, long, arg, help, func(val string) error {
+		*p = val
+		return nil
+	})
+}
+
+// Int defines an option with an integer value.
+func (f *FlagSet) Int(p *int, short rune, long, arg, help string) {
+	f.Func(short, long, arg, help, func(val string) error {
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		*p = v
+		return nil
+	})
+}
+
+// OptionalString defines an option whose value, if any, follows =
+// as in --color=WHEN. Without one *p is set to def.
+func (f *FlagSet) OptionalString(p *string, short rune, long, arg, def, help string) {
+	f.add(&option{short: short, long: long, arg: arg, help: help, optional: true, def: def,
+		set: func(val string) error {
+			*p = val
+			return nil
+		}})
+}
+
+// SingleDash lets the long options names be given after a single dash
+// too, as in -json. It is meant for the names a command took that way
+// before combined short options, which would otherwise read -json as
+// -j -s...
+func (f *FlagSet) SingleDash(names ...string) {
+	if f.singleDash == nil {
+		f.singleDash = make(map[string]bool)
+	}
+	for _, name := range names {
+		f.singleDash[name] = true
+	}
+}
+
+// Func defines an option with a value handed to set.
+func (f *FlagSet) Func(short rune, long, arg, help string, set func(string) error) {
+	f.add(&option{short: short, long: long, arg: arg, help: help, set: set})
+}
+
+func (f *FlagSet) lookupLong(name string) *option {
+	for _, o := range f.options {
+		if o.long != "" && o.long == name {
+			return o
+		}
+	}
+	return nil
+}
+
+func (f *FlagSet) lookupShort(r rune) *option {
+	for _, o := range f.options {
+		if o.short != 0 && o.short == r {
+			return o
+		}
+	}
+	return nil
+}
+
+func (f *FlagSet) setOption(o *option, val string) error {
+	if err := o.set(val); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %v", val, o.name(), err)
+	}
+	return nil
+}
+
+// parseLong parses the long option arg, without its dashes, taking its
+// value from rest when needed. It returns how many of rest it used.
+func (f *FlagSet) parseLong(arg string, rest []string, dashes string) (int, error) {
+	name, val, hasVal := arg, "", false
+	if i := strings.IndexByte(arg, '='); i >= 0 {
+		name, val, hasVal = arg[:i], arg[i+1:], true
+	}
+
+	switch name {
+	case "help":
+		return 0, ErrHelp
+	case "version":
+		return 0, ErrVersion
+	}
+
+	o := f.lookupLong(name)
+	if o == nil && name == "verbose" && !hasVal && !f.UnknownAsOperand {
+		f.verbose = true
+		return 0, nil
+	}
+	if o == nil {
+		return 0, errors.New("unknown option " + dashes + name)
+	}
+	if o.isBool {
+		if !hasVal {
+			val = "true"
+		}
+		return 0, f.setOption(o, val)
+	}
+	if hasVal {
+		return 0, f.setOption(o, val)
+	}
+	if o.optional {
+		return 0, f.setOption(o, o.def)
+	}
+	if len(rest) == 0 {
+		return 0, errors.New(dashes + name + " needs a value")
+	}
+	return 1, f.setOption(o, rest[0])
+}
+
+// parseShorts parses the short options in arg, without its dash. The
+// first one taking a value takes the remaining letters or else the next
+// argument.
+func (f *FlagSet) parseShorts(arg string, rest []string) (int, error) {
+	for i, r := range arg {
+		o := f.lookupShort(r)
+		if o == nil && f.shortVerbose(r) {
+			f.verbose = true
+			continue
+		}
+		if o == nil {
+			return 0, errors.New("unknown option -" + string(r))
+		}
+		if o.isBool {
+			if err := f.setOption(o, "true"); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if val := arg[i+len(string(r)):]; val != "" {
+			return 0, f.setOption(o, val)
+		}
+		if o.optional {
+			return 0, f.setOption(o, o.def)
+		}
+		if len(rest) == 0 {
+			return 0, errors.New("-" + string(r) + " needs a value")
+		}
+		return 1, f.setOption(o, rest[0])
+	}
+	return 0, nil
+}
+
+// shortVerbose reports whether r is the built-in -v. echo and the like
+// print it instead, and --verbose too.
+func (f *FlagSet) shortVerbose(r rune) bool {
+	return r == 'v' && !f.UnknownAsOperand
+}
+
+// singleDashLong reports whether arg, without its dash, is a long
+// option allowed after a single dash.
+func (f *FlagSet) singleDashLong(arg string) bool {
+	name := strings.SplitN(arg, "=", 2)[0]
+	return f.singleDash[name] && f.lookupLong(name) != nil
+}
+
+// known reports whether arg, starting with a dash, is entirely made of
+// options of f.
+func (f *FlagSet) known(arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		name := strings.SplitN(arg[2:], "=", 2)[0]
+		return name == "help" || name == "version" ||
+			(name == "verbose" && !f.UnknownAsOperand) || f.lookupLong(name) != nil
+	}
+	if f.singleDashLong(arg[1:]) {
+		return true
+	}
+	for _, r := range arg[1:] {
+		o := f.lookupShort(r)
+		if o == nil {
+			return false
+		}
+		if !o.isBool {
+			return true
+		}
+	}
+	return true
+}
+
+// Parse parses args, the command line without the command name. The
+// operands are then available from Args.
+func (f *FlagSet) Parse(args []string) error {
+	f.args = nil
+	f.verbose = false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--" && f.UnknownAsOperand:
+			f.args = append(f.args, args[i:]...)
+			return nil
+		case arg == "--":
+			f.args = append(f.args, args[i+1:]...)
+			return nil
+		case len(arg) < 2 || arg[0] != '-':
+			f.args = append(f.args, arg)
+			if f.StopAtOperand {
+				f.args = append(f.args, args[i+1:]...)
+				return nil
+			}
+			continue
+		case f.UnknownAsOperand && !f.known(arg):
+			f.args = append(f.args, args[i:]...)
+			return nil
+		}
+
+		var used int
+		var err error
+		if strings.HasPrefix(arg, "--") {
+			used, err = f.parseLong(arg[2:], args[i+1:], "--")
+		} else if f.singleDashLong(arg[1:]) {
+			used, err = f.parseLong(arg[1:], args[i+1:], "-")
+		} else {
+			used, err = f.parseShorts(arg[1:], args[i+1:])
+		}
+		if err != nil {
+			return err
+		}
+		i += used
+	}
+	return nil
+}
+
+// Args returns the operands left by Parse.
+func (f *FlagSet) Args() []string {
+	return f.args
+}
+
+// PrintUsage writes the synopsis and the options of the command.
+func (f *FlagSet) PrintUsage(w io.Writer) {
+	for i, line := range f.synopsis {
+		if i == 0 {
+			fmt.Fprintf(w, "Usage: %s %s\n", f.name, line)
+		} else {
+			fmt.Fprintf(w, "  or:  %s %s\n", f.name, line)
+		}
+	}
+	if len(f.synopsis) == 0 {
+		fmt.Fprintf(w, "Usage: %s\n", f.name)
+	}
+
+	var cols, helps []string
+	for _, o := range f.options {
+		col := "    "
+		if o.short != 0 {
+			col = "-" + string(o.short)
+			if o.long != "" {
+				col += ", "
+			} else if o.arg != "" {
+				col += " " + o.arg
+			}
+		}
+		if o.long != "" {
+			col += "--" + o.long
+			if o.optional {
+				col += "[=" + o.arg + "]"
+			} else if o.arg != "" {
+				col += "=" + o.arg
+			}
+		}
+		cols = append(cols, col)
+		helps = append(helps, o.help)
+	}
+	verbose := "-v, --verbose"
+	if f.lookupShort('v') != nil {
+		verbose = "    --verbose"
+	}
+	if f.lookupLong("verbose") == nil && !f.UnknownAsOperand {
+		cols = append(cols, verbose)
+		helps = append(helps, "name the failed system calls in diagnostics")
+	}
+	cols = append(cols, "    --help", "    --version")
+	helps = append(helps, "print this help and exit", "print the version and exit")
+
+	width := 0
+	for _, col := range cols {
+		if len(col) > width {
+			width = len(col)
+		}
+	}
+	fmt.Fprintln(w, "\nOptions:")
+	for i, col := range cols {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, col, helps[i])
+	}
+}
+
+// Report handles an error from parsing the command line and returns
+// the exit status. --help and --version print to stdout for status 0,
+// other errors go to stderr along with the usage for status 2.
+func (f *FlagSet) Report(err error, stdout, stderr io.Writer) int {
+	switch err {
+	case ErrHelp:
+		f.PrintUsage(stdout)
+		return 0
+	case ErrVersion:
+		fmt.Fprintf(stdout, "%s (enzo) %s\n", f.name, Version)
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "%s: %s\n", f.name, err)
+	f.PrintUsage(stderr)
+	return 2
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testOptions struct {
+	all, long, safe bool
+	width           int
+	color, ignore   string
+	asc             bool
+	sort            string
+}
+
+func newTestFlags(opts *testOptions) *FlagSet {
+	f := NewFlagSet("test", "[OPTION]... [FILE]...")
+	f.Bool(&opts.all, 'a', "all", "show all")
+	f.Bool(&opts.long, 'l', "", "long listing")
+	f.Bool(&opts.safe, 0, "safe", "be safe")
+	f.Int(&opts.width, 'w', "width", "N", "width")
+	f.String(&opts.color, 0, "color", "WHEN", "colorize")
+	f.String(&opts.ignore, 'I', "", "PATTERN", "ignore PATTERN")
+	f.BoolFunc(0, "freq-asc", "ascending", func() { opts.asc = true })
+	f.OptionalString(&opts.sort, 0, "sort", "WORD", "name", "sort by WORD")
+	f.SingleDash("safe", "color", "freq-asc")
+	return f
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected testOptions
+		operands []string
+	}{
+		{[]string{}, testOptions{}, nil},
+		{[]string{"-la"}, testOptions{all: true, long: true}, nil},
+		{[]string{"-l", "x", "-a"}, testOptions{all: true, long: true}, []string{"x"}},
+		{[]string{"--all", "--width=5", "f"}, testOptions{all: true, width: 5}, []string{"f"}},
+		{[]string{"--width", "5"}, testOptions{width: 5}, nil},
+		{[]string{"-w5", "-lw", "7"}, testOptions{long: true, width: 7}, nil},
+		{[]string{"-aw", "9"}, testOptions{all: true, width: 9}, nil},
+		{[]string{"-safe", "-color", "auto"}, testOptions{safe: true, color: "auto"}, nil},
+		{[]string{"-color=never"}, testOptions{color: "never"}, nil},
+		{[]string{"-I", "*.o", "-I*.a"}, testOptions{ignore: "*.a"}, nil},
+		{[]string{"--all=false", "-a"}, testOptions{all: true}, nil},
+		{[]string{"-freq-asc"}, testOptions{asc: true}, nil},
+		{[]string{"-a", "--", "-l", "--all"}, testOptions{all: true}, []string{"-l", "--all"}},
+		{[]string{"-", "-a"}, testOptions{all: true}, []string{"-"}},
+		// only the names given to SingleDash take a single dash
+		{[]string{"-all"}, testOptions{all: true, long: true}, nil},
+		{[]string{"--sort", "x"}, testOptions{sort: "name"}, []string{"x"}},
+		{[]string{"--sort=size"}, testOptions{sort: "size"}, nil},
+	}
+
+	for i, test := range tests {
+		var opts testOptions
+		f := newTestFlags(&opts)
+		if err := f.Parse(test.args); err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if opts != test.expected {
+			t.Errorf("test index %d: expected %+v, got %+v", i, test.expected, opts)
+		}
+		if fmt.Sprint(f.Args()) != fmt.Sprint(test.operands) {
+			t.Errorf("test index %d: expected operands %q, got %q", i, test.operands, f.Args())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-x"},
+		{"-lx"},
+		{"--nope"},
+		{"-w"},
+		{"--width"},
+		{"-w", "five"},
+		{"--all=maybe"},
+		{"--freq-asc=no"},
+		{"-width=5"},
+		{"-sort"},
+	} {
+		var opts testOptions
+		if err := newTestFlags(&opts).Parse(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+
+	var opts testOptions
+	f := newTestFlags(&opts)
+	if err := f.Parse([]string{"-a", "--help"}); err != ErrHelp {
+		t.Errorf("expected ErrHelp, got %v", err)
+	}
+	if err := f.Parse([]string{"--version"}); err != ErrVersion {
+		t.Errorf("expected ErrVersion, got %v", err)
+	}
+}
+
+func TestStopAtOperand(t *testing.T) {
+	var opts testOptions
+	f := newTestFlags(&opts)
+	f.StopAtOperand = true
+
+	if err := f.Parse([]string{"-l", "x", "-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.all || !opts.long || fmt.Sprint(f.Args()) != "[x -a]" {
+		t.Errorf("unexpected %+v %q", opts, f.Args())
+	}
+}
+
+func TestUnknownAsOperand(t *testing.T) {
+	var n bool
+	f := NewFlagSet("echo", "[-n] [STRING]...")
+	f.Bool(&n, 'n', "", "no newline")
+	f.UnknownAsOperand = true
+	f.StopAtOperand = true
+
+	tests := []struct {
+		args     []string
+		n        bool
+		operands string
+	}{
+		{[]string{"-n", "hi"}, true, "[hi]"},
+		{[]string{"-x", "-n"}, false, "[-x -n]"},
+		{[]string{"-nx"}, false, "[-nx]"},
+		{[]string{"hi", "-n"}, false, "[hi -n]"},
+		{[]string{"-nn", "--", "hi"}, true, "[-- hi]"},
+		{[]string{"--", "-n"}, false, "[-- -n]"},
+		{[]string{"-n", "--verbose", "hi"}, true, "[--verbose hi]"},
+		{[]string{"-v"}, false, "[-v]"},
+	}
+
+	for i, test := range tests {
+		n = false
+		if err := f.Parse(test.args); err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if n != test.n || fmt.Sprint(f.Args()) != test.operands {
+			t.Errorf("test index %d: got %v %q", i, n, f.Args())
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	var opts testOptions
+	f := newTestFlags(&opts)
+	var stdout, stderr bytes.Buffer
+
+	if status := f.Report(ErrHelp, &stdout, &stderr); status != 0 || stderr.Len() != 0 {
+		t.Errorf("help: status %d, stderr %q", status, stderr.String())
+	}
+	usage := stdout.String()
+	for _, want := range []string{
+		"Usage: test [OPTION]... [FILE]...\n",
+		"  -a, --all          show all\n",
+		"  -l                 long listing\n",
+		"      --safe         be safe\n",
+		"  -w, --width=N      width\n",
+		"      --color=WHEN   colorize\n",
+		"  -I PATTERN         ignore PATTERN\n",
+		"      --sort[=WORD]  sort by WORD\n",
+		"  -v, --verbose      name the failed system calls in diagnostics\n",
+		"      --help         print this help and exit\n",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage lacks %q:\n%s", want, usage)
+		}
+	}
+
+	stdout.Reset()
+	if status := f.Report(ErrVersion, &stdout, &stderr); status != 0 || stdout.String() != "test (enzo) dev\n" {
+		t.Errorf("version: status %d, stdout %q", status, stdout.String())
+	}
+
+	stdout.Reset()
+	err := f.Parse([]string{"-x"})
+	if status := f.Report(err, &stdout, &stderr); status != 2 || stdout.Len() != 0 {
+		t.Errorf("error: status %d, stdout %q", status, stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "test: unknown option -x\nUsage: test ") {
+		t.Errorf("error: stderr %q", stderr.String())
+	}
+}
+
+func TestNameFromPath(t *testing.T) {
+	f := NewFlagSet(filepath.Join("tmp", "bin", "ls"), "[FILE]...")
+	if name := f.Name(); name != "ls" {
+		t.Errorf("expected ls, got %q", name)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if f.Report(ErrVersion, &stdout, &stderr); stdout.String() != "ls (enzo) dev\n" {
+		t.Errorf("version: stdout %q", stdout.String())
+	}
+	f.Diag(&stderr, nil).Error("x", errors.New("failed"))
+	if stderr.String() != "ls: x: failed\n" {
+		t.Errorf("diag: stderr %q", stderr.String())
+	}
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Diag writes the diagnostics of a command on stderr, one per line as
+// "name: context: message". Failures there make the exit status 1, and
+// bad usage, reported by Report, makes it 2.
+type Diag struct {
+	name    string
+	w       io.Writer
+	verbose bool
+}
+
+// Diag returns the diagnostics of the command. They name the failed
+// system call and errno too when -v or --verbose was given, or when
+// ENZO_DEBUG is set in env to anything but "" or "0".
+func (f *FlagSet) Diag(stderr io.Writer, env []string) *Diag {
+	verbose := f.verbose
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "ENZO_DEBUG=") {
+			val := kv[len("ENZO_DEBUG="):]
+			verbose = f.verbose || (val != "" && val != "0")
+		}
+	}
+	return &Diag{name: f.name, w: stderr, verbose: verbose}
+}
+
+// Error writes err in context, usually the file or process it is about.
+// An empty context is taken from err when it is an *os.PathError or
+// *os.LinkError, leaving out the operation from the message. Joined
+// errors are written one per line.
+func (d *Diag) Error(context string, err error) {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range errs.Unwrap() {
+			d.Error(context, err)
+		}
+		return
+	}
+
+	msg := err.Error()
+	switch e := err.(type) {
+	case *os.PathError:
+		if context == "" {
+			context = e.Path
+		}
+		msg = e.Err.Error()
+	case *os.LinkError:
+		if context == "" {
+			context = e.New
+		}
+		msg = e.Err.Error()
+	case *os.SyscallError:
+		msg = e.Err.Error()
+	}
+
+	line := d.name + ": "
+	if context != "" {
+		line += context + ": "
+	}
+	line += msg
+	if d.verbose {
+		line += details(err)
+	}
+	fmt.Fprintln(d.w, line)
+}
+
+// details returns the system call and errno under err, as in
+// " (open, ENOENT)", or "" when there are none.
+func details(err error) string {
+	var (
+		pathErr *os.PathError
+		linkErr *os.LinkError
+		sysErr  *os.SyscallError
+		errno   syscall.Errno
+		list    []string
+	)
+	switch {
+	case errors.As(err, &pathErr):
+		list = append(list, pathErr.Op)
+	case errors.As(err, &linkErr):
+		list = append(list, linkErr.Op)
+	case errors.As(err, &sysErr):
+		list = append(list, sysErr.Syscall)
+	}
+	if errors.As(err, &errno) {
+		list = append(list, errnoName(errno))
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(list, ", ") + ")"
+}
+
+var errnoNames = map[syscall.Errno]string{
+	syscall.EPERM:        "EPERM",
+	syscall.ENOENT:       "ENOENT",
+	syscall.ESRCH:        "ESRCH",
+	syscall.EINTR:        "EINTR",
+	syscall.EIO:          "EIO",
+	syscall.EBADF:        "EBADF",
+	syscall.EAGAIN:       "EAGAIN",
+	syscall.ENOMEM:       "ENOMEM",
+	syscall.EACCES:       "EACCES",
+	syscall.EEXIST:       "EEXIST",
+	syscall.EXDEV:        "EXDEV",
+	syscall.ENOTDIR:      "ENOTDIR",
+	syscall.EISDIR:       "EISDIR",
+	syscall.EINVAL:       "EINVAL",
+	syscall.EMFILE:       "EMFILE",
+	syscall.ENOSPC:       "ENOSPC",
+	syscall.EROFS:        "EROFS",
+	syscall.EPIPE:        "EPIPE",
+	syscall.ENAMETOOLONG: "ENAMETOOLONG",
+	syscall.ENOTEMPTY:    "ENOTEMPTY",
+	syscall.ELOOP:        "ELOOP",
+}
+
+// errnoName returns the symbolic name of errno, as in errno.h.
+func errnoName(errno syscall.Errno) string {
+	if name, ok := errnoNames[errno]; ok {
+		return name
+	}
+	return fmt.Sprintf("errno %d", uintptr(errno))
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestDiag(t *testing.T) {
+	notFound := &os.PathError{Op: "open", Path: "f", Err: syscall.ENOENT}
+	tests := []struct {
+		context  string
+		err      error
+		expected string
+		verbose  string
+	}{
+		{"", notFound, "test: f: no such file or directory\n", " (open, ENOENT)"},
+		{"g", notFound, "test: g: no such file or directory\n", " (open, ENOENT)"},
+		{"42", os.NewSyscallError("kill", syscall.ESRCH), "test: 42: no such process\n", " (kill, ESRCH)"},
+		{"", &os.LinkError{Op: "symlink", Old: "a", New: "b", Err: syscall.EEXIST}, "test: b: file exists\n", " (symlink, EEXIST)"},
+		{"", errors.New("bad input"), "test: bad input\n", ""},
+		{"write error", syscall.EPIPE, "test: write error: broken pipe\n", " (EPIPE)"},
+	}
+
+	for _, test := range tests {
+		var stderr bytes.Buffer
+		f := NewFlagSet("test")
+		f.Diag(&stderr, nil).Error(test.context, test.err)
+		if stderr.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, stderr.String())
+		}
+
+		stderr.Reset()
+		f.Diag(&stderr, []string{"ENZO_DEBUG=1"}).Error(test.context, test.err)
+		verbose := test.expected[:len(test.expected)-1] + test.verbose + "\n"
+		if stderr.String() != verbose {
+			t.Errorf("expected %q, got %q", verbose, stderr.String())
+		}
+	}
+}
+
+func TestDiagJoined(t *testing.T) {
+	var stderr bytes.Buffer
+	err := errors.Join(
+		&os.PathError{Op: "stat", Path: "a", Err: syscall.ENOENT},
+		&os.PathError{Op: "open", Path: "b", Err: syscall.EACCES},
+	)
+	NewFlagSet("ls").Diag(&stderr, nil).Error("", err)
+
+	if expected := "ls: a: no such file or directory\nls: b: permission denied\n"; stderr.String() != expected {
+		t.Errorf("expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	err := &os.PathError{Op: "open", Path: "f", Err: syscall.ENOENT}
+	tests := []struct {
+		args     []string
+		env      []string
+		expected string
+	}{
+		{nil, nil, "test: f: no such file or directory\n"},
+		{[]string{"-v"}, nil, "test: f: no such file or directory (open, ENOENT)\n"},
+		{[]string{"-av"}, nil, "test: f: no such file or directory (open, ENOENT)\n"},
+		{[]string{"--verbose"}, nil, "test: f: no such file or directory (open, ENOENT)\n"},
+		{nil, []string{"ENZO_DEBUG=yes"}, "test: f: no such file or directory (open, ENOENT)\n"},
+		{nil, []string{"ENZO_DEBUG=1", "ENZO_DEBUG=0"}, "test: f: no such file or directory\n"},
+		{nil, []string{"ENZO_DEBUG="}, "test: f: no such file or directory\n"},
+	}
+
+	for _, test := range tests {
+		var opts testOptions
+		f := newTestFlags(&opts)
+		if err := f.Parse(test.args); err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+
+		var stderr bytes.Buffer
+		f.Diag(&stderr, test.env).Error("", err)
+		if stderr.String() != test.expected {
+			t.Errorf("%v %v: expected %q, got %q", test.args, test.env, test.expected, stderr.String())
+		}
+	}
+
+	// echo prints -v
+	var n bool
+	f := NewFlagSet("echo")
+	f.Bool(&n, 'n', "", "no newline")
+	f.UnknownAsOperand = true
+	if err := f.Parse([]string{"-v", "x"}); err != nil || len(f.Args()) != 2 {
+		t.Errorf("echo -v: %v %q", err, f.Args())
+	}
+}
@@ -0,0 +1,76 @@
+package kill
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/c0defellas/enzo/internal/cli"
+)
+
+func sliceatoi(strNumbers []string) ([]int, error) {
+	numbers := make([]int, 0, len(strNumbers))
+
+	for _, str := range strNumbers {
+		i, err := strconv.Atoi(str)
+
+		if err != nil {
+			return []int{}, fmt.Errorf("invalid pid %q", str)
+		}
+
+		numbers = append(numbers, i)
+	}
+
+	return numbers, nil
+}
+
+func parseargs(args []string) ([]int, bool, *cli.FlagSet, error) {
+	var safe bool
+
+	name := "kill"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	flags := cli.NewFlagSet(name, "[-safe] PID...")
+	flags.Bool(&safe, 0, "safe", "doesn't use SIGKILL when SIGTERM fail (unix systems)")
+	// as the flag package read it
+	flags.SingleDash("safe")
+	if err := flags.Parse(args); err != nil {
+		return nil, false, flags, err
+	}
+
+	pids, err := sliceatoi(flags.Args())
+	if err != nil {
+		return nil, false, flags, err
+	}
+	if len(pids) == 0 {
+		return nil, false, flags, errors.New("no pids to kill")
+	}
+
+	return pids, safe, flags, nil
+}
+
+// Run runs kill with args, args[0] being the command name, returning
+// the exit status. stdin is unused, env may turn on verbose
+// diagnostics with ENZO_DEBUG.
+func Run(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int {
+	pids, safe, flags, err := parseargs(args)
+	if err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+	errs := kill(pids, safe)
+
+	// some went wrong
+	if len(errs) > 0 {
+		diag := flags.Diag(stderr, env)
+		for _, pid := range pids {
+			if err, ok := errs[pid]; ok {
+				diag.Error(strconv.Itoa(pid), err)
+			}
+		}
+
+		return 1
+	}
+	return 0
+}
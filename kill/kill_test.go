@@ -1,10 +1,12 @@
-package main
+package kill
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"os/exec"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -56,7 +58,7 @@ func testKill(t *testing.T, safe bool, cmds []*exec.Cmd) {
 
 	for _, cmd := range cmds {
 		if state := cmd.ProcessState; state.Success() {
-			t.Errorf("Process finished successfully (wasn't killed): %v", cmd.Process.Pid, state)
+			t.Errorf("Process %d finished successfully (wasn't killed): %v", cmd.Process.Pid, state)
 			return
 		}
 	}
@@ -95,3 +97,42 @@ func TestKillUnsafe(t *testing.T) {
 		})
 	}
 }
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{
+		{"kill"},
+		{"kill", "notapid"},
+		{"kill", "-nosuchflag", "1"},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		if status := Run(nil, &stdout, &stderr, args, nil); status != 2 {
+			t.Errorf("%v: expected status 2, got %d", args, status)
+		}
+		if stdout.Len() != 0 || !strings.Contains(stderr.String(), "Usage: kill") {
+			t.Errorf("%v: expected the usage on stderr, got %q", args, stderr.String())
+		}
+	}
+}
+
+func TestRunNoSuchProcess(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	// above the largest pid_max of linux, 2^22
+	args := []string{"kill", "99999998", "99999999"}
+	if status := Run(nil, &stdout, &stderr, args, nil); status != 1 {
+		t.Errorf("expected status 1, got %d", status)
+	}
+	expected := "kill: 99999998: no such process\nkill: 99999999: no such process\n"
+	if stdout.Len() != 0 || stderr.String() != expected {
+		t.Errorf("expected stderr %q, got stdout %q stderr %q", expected, stdout.String(), stderr.String())
+	}
+
+	stderr.Reset()
+	Run(nil, &stdout, &stderr, []string{"kill", "-v", "99999999"}, nil)
+	if expected := "kill: 99999999: no such process (kill, ESRCH)\n"; stderr.String() != expected {
+		t.Errorf("expected stderr %q, got %q", expected, stderr.String())
+	}
+}
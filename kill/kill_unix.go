@@ -1,8 +1,10 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
 // +build linux darwin dragonfly freebsd netbsd openbsd
 
-package main
+package kill
 
 import (
+	"os"
 	"syscall"
 )
 
@@ -14,14 +16,14 @@ func kill(pids []int, safe bool) map[int]error {
 
 		if err != nil {
 			if safe == true {
-				errs[pid] = err
+				errs[pid] = os.NewSyscallError("kill", err)
 				continue
 			}
 
 			err = syscall.Kill(pid, syscall.SIGKILL)
 
 			if err != nil {
-				errs[pid] = err
+				errs[pid] = os.NewSyscallError("kill", err)
 				continue
 			}
 		}
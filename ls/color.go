@@ -0,0 +1,209 @@
+package ls
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// defaultColors follows the dircolors database shipped with GNU
+// coreutils, reduced to the entries that make sense here.
+const defaultColors = "di=01;34:ln=01;36:pi=40;33:so=01;35:bd=40;33;01:" +
+	"cd=40;33;01:or=40;31;01:ex=01;32:su=37;41:sg=30;43:st=37;44:" +
+	"tw=30;42:ow=34;42:" +
+	"*.tar=01;31:*.tgz=01;31:*.gz=01;31:*.bz2=01;31:*.xz=01;31:" +
+	"*.zst=01;31:*.zip=01;31:*.jpg=01;35:*.jpeg=01;35:*.png=01;35:" +
+	"*.gif=01;35:*.svg=01;35:*.mp3=00;36:*.ogg=00;36:*.wav=00;36"
+
+type colorSuffix struct {
+	suffix string
+	code   string
+}
+
+// lsColors holds the SGR codes used to colour file names, keyed by the
+// two letter file types of LS_COLORS, plus the name suffix rules.
+type lsColors struct {
+	types    map[string]string
+	suffixes []colorSuffix
+}
+
+// decorator rewrites the name of fileInfo as printed by a formatter.
+type decorator func(fileInfo os.FileInfo, name string) string
+
+// parseColors reads spec in the LS_COLORS format on top of the built-in
+// defaults. Malformed entries are ignored, like GNU ls does.
+func parseColors(spec string) *lsColors {
+	colors := &lsColors{types: make(map[string]string)}
+
+	for _, s := range []string{defaultColors, spec} {
+		for _, entry := range strings.Split(s, ":") {
+			kv := strings.SplitN(entry, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				continue
+			}
+			if strings.HasPrefix(kv[0], "*") {
+				colors.setSuffix(kv[0][1:], kv[1])
+				continue
+			}
+			colors.types[kv[0]] = kv[1]
+		}
+	}
+
+	return colors
+}
+
+func (c *lsColors) setSuffix(suffix, code string) {
+	for i := range c.suffixes {
+		if c.suffixes[i].suffix == suffix {
+			c.suffixes[i].code = code
+			return
+		}
+	}
+	c.suffixes = append(c.suffixes, colorSuffix{suffix, code})
+}
+
+func (c *lsColors) suffixCode(name string) string {
+	var match colorSuffix
+
+	for _, s := range c.suffixes {
+		if strings.HasSuffix(name, s.suffix) && len(s.suffix) > len(match.suffix) {
+			match = s
+		}
+	}
+	return match.code
+}
+
+// colorKey returns the LS_COLORS key matching the type and permissions
+// of mode, or "" for plain files.
+func colorKey(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		switch {
+		case mode&os.ModeSticky != 0 && mode&0002 != 0:
+			return "tw"
+		case mode&0002 != 0:
+			return "ow"
+		case mode&os.ModeSticky != 0:
+			return "st"
+		}
+		return "di"
+	case mode&os.ModeSymlink != 0:
+		return "ln"
+	case mode&os.ModeNamedPipe != 0:
+		return "pi"
+	case mode&os.ModeSocket != 0:
+		return "so"
+	case mode&os.ModeCharDevice != 0:
+		return "cd"
+	case mode&os.ModeDevice != 0:
+		return "bd"
+	case mode&os.ModeSetuid != 0:
+		return "su"
+	case mode&os.ModeSetgid != 0:
+		return "sg"
+	case mode&0111 != 0:
+		return "ex"
+	}
+	return ""
+}
+
+func (c *lsColors) code(fileInfo os.FileInfo) string {
+	key := colorKey(fileInfo.Mode())
+
+	if _, ok := headerOf(fileInfo); ok && key == "ln" {
+		return c.types["ln"]
+	}
+	if key == "ln" {
+		target, err := os.Stat(entryPath(fileInfo))
+		if err != nil {
+			return c.types["or"]
+		}
+		if c.types["ln"] == "target" {
+			return c.code(target)
+		}
+	}
+
+	if code, ok := c.types[key]; ok && key != "" {
+		return code
+	}
+	if code := c.suffixCode(fileInfo.Name()); code != "" {
+		return code
+	}
+	return c.types["fi"]
+}
+
+func (c *lsColors) colorize(fileInfo os.FileInfo, name string) string {
+	code := c.code(fileInfo)
+	if code == "" || code == "0" || code == "00" {
+		return name
+	}
+	return "\x1b[" + code + "m" + name + "\x1b[0m"
+}
+
+// indicator returns the -F type suffix of mode. With dirsOnly it
+// returns just the "/" of directories, as -p does.
+func indicator(mode os.FileMode, dirsOnly bool) string {
+	switch {
+	case mode.IsDir():
+		return "/"
+	case dirsOnly:
+		return ""
+	case mode&os.ModeSymlink != 0:
+		return "@"
+	case mode&os.ModeNamedPipe != 0:
+		return "|"
+	case mode&os.ModeSocket != 0:
+		return "="
+	case mode.IsRegular() && mode&0111 != 0:
+		return "*"
+	}
+	return ""
+}
+
+func classify(dirsOnly bool) decorator {
+	return func(fileInfo os.FileInfo, name string) string {
+		return name + indicator(fileInfo.Mode(), dirsOnly)
+	}
+}
+
+// decorate wraps fn so the file name ending each line it prints is
+// passed through decs, in order.
+func (f listFormat) decorate(fn formatter, decs ...decorator) formatter {
+	return func(fileInfo os.FileInfo) (string, error) {
+		txt, err := fn(fileInfo)
+		if err != nil {
+			return "", err
+		}
+
+		name := f.formatFileName(fileInfo.Name())
+		tail := "\n"
+		if !strings.HasSuffix(txt, name+tail) {
+			link, err := f.linkSuffix(fileInfo)
+			if err != nil || !strings.HasSuffix(txt, name+link+"\n") {
+				return txt, nil
+			}
+			tail = link + "\n"
+		}
+
+		prefix := txt[:len(txt)-len(name)-len(tail)]
+		for _, dec := range decs {
+			name = dec(fileInfo, name)
+		}
+		return prefix + name + tail, nil
+	}
+}
+
+func useColor(mode string, isTerminal bool) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorAuto:
+		return isTerminal
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package ls
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultWidth = 80
+	columnGap    = 2
+)
+
+// columnWriter buffers the entries written to it and lays them out in
+// columns when flushed, down then across or, if across is set, across
+// then down.
+type columnWriter struct {
+	out    io.Writer
+	width  int
+	across bool
+	cells  []string
+}
+
+// Write takes p as a single entry, so names with newlines in them stay
+// in one cell.
+func (c *columnWriter) Write(p []byte) (int, error) {
+	c.cells = append(c.cells, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (c *columnWriter) Flush() error {
+	cells := c.cells
+	c.cells = nil
+	if len(cells) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(c.out, formatColumns(cells, c.width, c.across))
+	return err
+}
+
+// lineWriter returns the output under w when w lays entries out in
+// columns, for lines that are not entries, as the total line.
+func lineWriter(w io.Writer) io.Writer {
+	if c, ok := w.(*columnWriter); ok {
+		return c.out
+	}
+	return w
+}
+
+// displayWidth counts the runes of s, skipping SGR escape sequences.
+func displayWidth(s string) int {
+	width := 0
+	for len(s) > 0 {
+		if strings.HasPrefix(s, "\x1b[") {
+			end := strings.IndexByte(s, 'm')
+			if end < 0 {
+				break
+			}
+			s = s[end+1:]
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		width++
+	}
+	return width
+}
+
+func cellIndex(row, col, rows, cols int, across bool) int {
+	if across {
+		return row*cols + col
+	}
+	return col*rows + row
+}
+
+// columnWidths returns the width of every column when names are laid out
+// in cols columns, or nil if they don't fit in width.
+func columnWidths(names []string, width, cols int, across bool) []int {
+	rows := (len(names) + cols - 1) / cols
+	if !across {
+		cols = (len(names) + rows - 1) / rows
+	}
+
+	widths := make([]int, cols)
+	total := columnGap * (cols - 1)
+	for col := 0; col < cols; col++ {
+		for row := 0; row < rows; row++ {
+			i := cellIndex(row, col, rows, cols, across)
+			if i >= len(names) {
+				continue
+			}
+			if w := displayWidth(names[i]); w > widths[col] {
+				total += w - widths[col]
+				widths[col] = w
+			}
+		}
+		if total > width {
+			return nil
+		}
+	}
+
+	return widths
+}
+
+func formatColumns(names []string, width int, across bool) string {
+	maxCols := width/(1+columnGap) + 1
+	if maxCols > len(names) {
+		maxCols = len(names)
+	}
+
+	widths := []int{0}
+	for cols := maxCols; cols > 1; cols-- {
+		if w := columnWidths(names, width, cols, across); w != nil {
+			widths = w
+			break
+		}
+	}
+
+	cols := len(widths)
+	rows := (len(names) + cols - 1) / cols
+
+	var out bytes.Buffer
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			i := cellIndex(row, col, rows, cols, across)
+			if i >= len(names) {
+				break
+			}
+			if col > 0 {
+				out.WriteString(strings.Repeat(" ", columnGap))
+			}
+			out.WriteString(names[i])
+
+			next := cellIndex(row, col+1, rows, cols, across)
+			if col+1 < cols && next < len(names) {
+				out.WriteString(strings.Repeat(" ", widths[col]-displayWidth(names[i])))
+			}
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
+
+// outputWidth returns the width to lay columns out in and whether
+// stdout is a terminal. $COLUMNS takes precedence over the terminal size.
+func outputWidth(stdout io.Writer, env []string) (int, bool) {
+	var width int
+	var isTerminal bool
+	if f, ok := stdout.(interface{ Fd() uintptr }); ok {
+		width, isTerminal = terminalWidth(f.Fd())
+	}
+
+	if cols, err := strconv.Atoi(getenv(env, "COLUMNS")); err == nil && cols > 0 {
+		return cols, isTerminal
+	}
+	if width > 0 {
+		return width, isTerminal
+	}
+	return defaultWidth, isTerminal
+}
@@ -0,0 +1,47 @@
+package ls
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// nameFilter reports whether the directory entry called name is listed.
+type nameFilter func(name string) bool
+
+// patternList collects the shell patterns of a repeatable flag.
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return err
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+func (p patternList) matches(name string) bool {
+	for _, pattern := range p {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newNameFilter hides the entries matching ignore and, unless
+// showHidden is set, dotfiles and the entries matching hide.
+func newNameFilter(showHidden bool, ignore, hide patternList) nameFilter {
+	return func(name string) bool {
+		if ignore.matches(name) {
+			return false
+		}
+		if showHidden {
+			return true
+		}
+		return !strings.HasPrefix(name, ".") && !hide.matches(name)
+	}
+}
@@ -0,0 +1,675 @@
+package ls
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/c0defellas/enzo/internal/cli"
+)
+
+type formatter func(os.FileInfo) (string, error)
+
+// listFormat is how a run of ls writes names and sizes. Each Run builds
+// its own, so one run's options don't leak into the next.
+type listFormat struct {
+	quote     quoter
+	size      func(int64) string
+	blockSize int64
+}
+
+// defaultFormat is the format of ls without options, off a terminal.
+var defaultFormat = listFormat{
+	quote:     shellQuote,
+	size:      exactSize,
+	blockSize: defaultBlockSize,
+}
+
+type options struct {
+	list     bool
+	json     bool
+	ndjson   bool
+	columns  bool
+	across   bool
+	single   bool
+	color    string
+	classify bool
+	slash    bool
+	quoting  string
+	hideCtrl bool
+	unsorted bool
+	inode    bool
+	size     bool
+	context  bool
+	xattrs   bool
+	all      bool
+	almost   bool
+	dir      bool
+	ignore   patternList
+	hide     patternList
+	human    bool
+	si       bool
+	blocks   string
+	archive  bool
+}
+
+// fileEntry is an os.FileInfo that remembers the path it was read from.
+type fileEntry struct {
+	os.FileInfo
+	path string
+}
+
+// fileJSON is the machine-readable representation of a listed file.
+// Mode is the mode as -l prints it, as in "drwxr-xr-x".
+// ModeBits holds the mode as st_mode of stat(2) does, the S_IF* file
+// type along with the permission bits, on every system and for archive
+// members alike.
+type fileJSON struct {
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	Type       string     `json:"type"`
+	Mode       string     `json:"mode"`
+	ModeBits   uint32     `json:"mode_bits"`
+	Size       int64      `json:"size"`
+	UID        uint32     `json:"uid"`
+	GID        uint32     `json:"gid"`
+	User       string     `json:"user"`
+	Group      string     `json:"group"`
+	Nlink      uint64     `json:"nlink"`
+	Inode      uint64     `json:"inode"`
+	Mtime      time.Time  `json:"mtime"`
+	Atime      *time.Time `json:"atime,omitempty"`
+	Ctime      *time.Time `json:"ctime,omitempty"`
+	Btime      *time.Time `json:"btime,omitempty"`
+	LinkTarget string     `json:"link_target,omitempty"`
+	Whiteout   string     `json:"whiteout,omitempty"`
+}
+
+// jsonArray formats files as elements of a single JSON array.
+// end must be written after the last file.
+type jsonArray struct {
+	count int
+}
+
+func (f listFormat) formatFileName(name string) string {
+	return f.quote(name)
+}
+
+func fileOwner(fileInfo os.FileInfo) (string, string, error) {
+	if header, ok := headerOf(fileInfo); ok {
+		return headerOwner(header)
+	}
+
+	userName, err := lookupUser(fileInfo)
+	if err != nil {
+		return "", "", err
+	}
+	groupName, err := lookupGroup(fileInfo)
+	if err != nil {
+		return "", "", err
+	}
+	return userName, groupName, nil
+}
+
+func deviceSize(fileInfo os.FileInfo) (string, bool) {
+	major, minor, ok := deviceNumbers(fileInfo)
+	if header, isHeader := headerOf(fileInfo); isHeader {
+		major, minor = uint64(header.Devmajor), uint64(header.Devminor)
+		ok = fileInfo.Mode()&os.ModeDevice != 0
+	}
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d, %d", major, minor), true
+}
+
+// linkSuffix returns what -l prints after the name of a link: the
+// target of symlinks and of the hard links stored in archives.
+func (f listFormat) linkSuffix(fileInfo os.FileInfo) (string, error) {
+	if header, ok := headerOf(fileInfo); ok && header.Typeflag == tar.TypeLink {
+		return " link to " + f.formatFileName(header.Linkname), nil
+	}
+
+	target, err := linkTarget(fileInfo)
+	if err != nil || target == "" {
+		return "", err
+	}
+	return " -> " + f.formatFileName(target), nil
+}
+
+func (f listFormat) printFileList(fileInfo os.FileInfo) (string, error) {
+	userName, groupName, err := fileOwner(fileInfo)
+	if err != nil {
+		return "", err
+	}
+	link, err := f.linkSuffix(fileInfo)
+	if err != nil {
+		return "", err
+	}
+	size, ok := deviceSize(fileInfo)
+	if !ok {
+		size = f.size(fileInfo.Size())
+	}
+	return fmt.Sprintf(
+		"%s%s %s %s %6s %s %s%s\n",
+		modeString(fileInfo.Mode()),
+		securityMarker(fileInfo),
+		userName,
+		groupName,
+		size,
+		formatTime(fileInfo.ModTime(), time.Now()),
+		f.formatFileName(fileInfo.Name()),
+		link,
+	), nil
+}
+
+// formatTime returns the modification time t as -l prints it at now:
+// with the time of day in the last six months, with the year before
+// that or in the future.
+func formatTime(t, now time.Time) string {
+	t = t.Local()
+	if t.After(now) || now.Sub(t) > 182*24*time.Hour {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
+
+// blocks returns the number of blockSize blocks used by the file.
+func blocks(fileInfo os.FileInfo, blockSize int64) (int64, error) {
+	size := fileInfo.Size()
+	if _, ok := headerOf(fileInfo); !ok {
+		var err error
+		if size, err = allocatedSize(fileInfo); err != nil {
+			return 0, err
+		}
+	}
+	return (size + blockSize - 1) / blockSize, nil
+}
+
+func printInode(fileInfo os.FileInfo) (string, error) {
+	if _, ok := headerOf(fileInfo); ok {
+		return fmt.Sprintf("%8s ", "?"), nil
+	}
+	inode, err := inodeNumber(fileInfo)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%8d ", inode), nil
+}
+
+func (f listFormat) printBlocks(fileInfo os.FileInfo) (string, error) {
+	n, err := blocks(fileInfo, f.blockSize)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%4d ", n), nil
+}
+
+// prefixed wraps fn so every line it prints starts with the output of
+// prefix, as -i and -s do.
+func prefixed(fn formatter, prefix formatter) formatter {
+	return func(fileInfo os.FileInfo) (string, error) {
+		pre, err := prefix(fileInfo)
+		if err != nil {
+			return "", err
+		}
+		txt, err := fn(fileInfo)
+		if err != nil {
+			return "", err
+		}
+		return pre + txt, nil
+	}
+}
+
+func printTotal(files []os.FileInfo, blockSize int64) (string, error) {
+	var total int64
+	for _, f := range files {
+		n, err := blocks(f, blockSize)
+		if err != nil {
+			return "", err
+		}
+		total += n
+	}
+	return fmt.Sprintf("total %d\n", total), nil
+}
+
+func (f listFormat) printFileNames(fileInfo os.FileInfo) (string, error) {
+	return fmt.Sprintf("%s\n", f.formatFileName(fileInfo.Name())), nil
+}
+
+func entryPath(fileInfo os.FileInfo) string {
+	if entry, ok := fileInfo.(fileEntry); ok {
+		return entry.path
+	}
+	return fileInfo.Name()
+}
+
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "char"
+	case mode&os.ModeDevice != 0:
+		return "block"
+	case mode.IsRegular():
+		return "file"
+	}
+	return "irregular"
+}
+
+func linkTarget(fileInfo os.FileInfo) (string, error) {
+	if fileInfo.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+	if header, ok := headerOf(fileInfo); ok {
+		return header.Linkname, nil
+	}
+	return os.Readlink(entryPath(fileInfo))
+}
+
+func newFileJSON(fileInfo os.FileInfo) (*fileJSON, error) {
+	userName, groupName, err := fileOwner(fileInfo)
+	if err != nil {
+		return nil, err
+	}
+	target, err := linkTarget(fileInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &fileJSON{
+		Name:       fileInfo.Name(),
+		Path:       entryPath(fileInfo),
+		Type:       fileType(fileInfo.Mode()),
+		Mode:       modeString(fileInfo.Mode()),
+		ModeBits:   unixMode(fileInfo.Mode()),
+		Size:       fileInfo.Size(),
+		User:       userName,
+		Group:      groupName,
+		Mtime:      fileInfo.ModTime(),
+		LinkTarget: target,
+	}
+	if header, ok := headerOf(fileInfo); ok {
+		fillHeader(entry, header)
+		return entry, nil
+	}
+	if err := fillStat(entry, fileInfo); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// unixMode returns mode as st_mode of stat(2) holds it.
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+
+	switch {
+	case mode&os.ModeDir != 0:
+		bits |= 0040000
+	case mode&os.ModeSymlink != 0:
+		bits |= 0120000
+	case mode&os.ModeNamedPipe != 0:
+		bits |= 0010000
+	case mode&os.ModeSocket != 0:
+		bits |= 0140000
+	case mode&os.ModeCharDevice != 0:
+		bits |= 0020000
+	case mode&os.ModeDevice != 0:
+		bits |= 0060000
+	default:
+		bits |= 0100000
+	}
+	return bits
+}
+
+// modeString returns mode as ls prints it: the file type letter, one
+// of "dlpscb-", followed by the permissions with the setuid, setgid and
+// sticky bits in place of the execute ones.
+func modeString(mode os.FileMode) string {
+	buf := []byte("----------")
+	switch {
+	case mode.IsDir():
+		buf[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		buf[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		buf[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		buf[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		buf[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		buf[0] = 'b'
+	}
+
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			buf[i+1] = rwx[i]
+		}
+	}
+	special := func(i int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if buf[i] == 'x' {
+			buf[i] = c
+		} else {
+			buf[i] = c - 'a' + 'A'
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's')
+	special(6, mode&os.ModeSetgid != 0, 's')
+	special(9, mode&os.ModeSticky != 0, 't')
+	return string(buf)
+}
+
+func printFileNDJSON(fileInfo os.FileInfo) (string, error) {
+	entry, err := newFileJSON(fileInfo)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+func (a *jsonArray) format(fileInfo os.FileInfo) (string, error) {
+	txt, err := printFileNDJSON(fileInfo)
+	if err != nil {
+		return "", err
+	}
+
+	a.count++
+	if a.count == 1 {
+		return "[\n" + txt, nil
+	}
+	return "," + txt, nil
+}
+
+func (a *jsonArray) end() string {
+	if a.count == 0 {
+		return "[]\n"
+	}
+	return "]\n"
+}
+
+func ls(files []os.FileInfo, writer io.Writer, fn formatter) error {
+	for _, f := range files {
+		txt, err := fn(f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(writer, txt)
+	}
+	return nil
+}
+
+func runls(paths []string, writer io.Writer, fn formatter) error {
+	return runlsWith(paths, writer, fn, readOptions{blockSize: defaultBlockSize})
+}
+
+// runlsWith lists paths, going on with the next one when a path fails.
+// It returns the errors of all of them joined, each naming its path.
+func runlsWith(paths []string, writer io.Writer, fn formatter, ropts readOptions) error {
+	var errs []error
+	for _, path := range paths {
+		err := listPath(path, writer, fn, ropts)
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(*os.PathError); !ok {
+			err = &os.PathError{Op: "list", Path: path, Err: err}
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// listPath lists a single path of the command line.
+func listPath(path string, writer io.Writer, fn formatter, ropts readOptions) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	files := []os.FileInfo{fileEntry{fileInfo, path}}
+	switch {
+	case ropts.archive:
+		files = nil
+		err = listArchive(path, writer, fn, ropts)
+	case !fileInfo.IsDir() || ropts.directory:
+	case ropts.unsorted:
+		files = nil
+		err = streamDir(path, writer, fn, ropts)
+	default:
+		files, err = readDir(path, ropts)
+		if err == nil && ropts.total {
+			var total string
+			total, err = printTotal(files, ropts.blockSize)
+			fmt.Fprint(lineWriter(writer), total)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ls(files, writer, fn)
+	if err != nil {
+		return err
+	}
+
+	if flusher, ok := writer.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseargs(args []string) ([]string, options, *cli.FlagSet, error) {
+	var opts options
+
+	name := "ls"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	flags := cli.NewFlagSet(name, "[OPTION]... [FILE]...")
+
+	opts.color = colorNever
+	flags.Bool(&opts.list, 'l', "", "use a long listing format")
+	flags.Bool(&opts.json, 0, "json", "print a JSON array of file objects")
+	flags.Bool(&opts.ndjson, 0, "ndjson", "print one JSON object per line")
+	flags.Bool(&opts.columns, 'C', "", "list entries by columns")
+	flags.Bool(&opts.across, 'x', "", "list entries by lines instead of by columns")
+	flags.Bool(&opts.single, '1', "", "list one file per line")
+	flags.OptionalString(&opts.color, 0, "color", "WHEN", colorAlways, "colorize names: auto, always or never, always if WHEN is left out")
+	flags.Bool(&opts.classify, 'F', "classify", "append an indicator (one of */=@|) to entries")
+	flags.Bool(&opts.slash, 'p', "", "append / indicator to directories")
+	flags.String(&opts.quoting, 0, "quoting-style", "WORD",
+		"quote names as literal, shell, shell-escape, c or escape")
+	// -b and --quoting-style both set the style, the last one wins
+	flags.BoolFunc('b', "escape", "print C-style escapes for nongraphic characters", func() {
+		opts.quoting = quoteEscape
+	})
+	flags.Bool(&opts.hideCtrl, 'q', "hide-control-chars", "print ? instead of nongraphic characters")
+	flags.Bool(&opts.unsorted, 'U', "", "do not sort; stream entries in directory order, with no total line")
+	flags.Bool(&opts.inode, 'i', "inode", "print the index number of each file")
+	flags.Bool(&opts.size, 's', "size", "print the allocated size of each file, in blocks")
+	flags.Bool(&opts.context, 'Z', "context", "print the SELinux security context of each file")
+	flags.Bool(&opts.xattrs, '@', "", "with -l, list extended attributes, capabilities and ACLs")
+	flags.Bool(&opts.all, 'a', "all", "do not ignore entries starting with .")
+	flags.Bool(&opts.almost, 'A', "almost-all", "do not list implied . and ..")
+	flags.Bool(&opts.dir, 'd', "directory", "list directories themselves, not their contents")
+	flags.Func('I', "ignore", "PATTERN", "do not list entries matching the shell PATTERN", opts.ignore.Set)
+	flags.Bool(&opts.human, 'h', "human-readable", "with -l, print sizes like 1.00K, 234.00M, 2.00G")
+	flags.Bool(&opts.si, 0, "si", "like -h, but use powers of 1000 not 1024")
+	flags.String(&opts.blocks, 0, "block-size", "SIZE", "scale sizes by SIZE, e.g. K, 4K, MB, MiB or 512")
+	flags.Bool(&opts.archive, 0, "tar", "list the members of tar, tar.gz or OCI layer files")
+	flags.Func(0, "hide", "PATTERN",
+		"do not list entries matching the shell PATTERN (overridden by -a or -A)", opts.hide.Set)
+
+	// baseline ls had only -l, but the long options added before combined
+	// short options were written after a single dash; not -si, which is
+	// now -s -i
+	flags.SingleDash("json", "ndjson", "color", "quoting-style", "block-size", "tar", "hide")
+	if err := flags.Parse(args); err != nil {
+		return nil, opts, flags, err
+	}
+
+	if len(flags.Args()) > 0 {
+		return flags.Args(), opts, flags, nil
+	}
+
+	return []string{"."}, opts, flags, nil
+}
+
+// Run runs ls with args, args[0] being the command name, and env in
+// the form of os.Environ, returning the exit status: 1 when some path
+// couldn't be listed.
+func Run(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int {
+	paths, opts, flags, err := parseargs(args)
+	if err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+
+	var array jsonArray
+	var writer io.Writer = stdout
+
+	width, isTerminal := outputWidth(stdout, env)
+
+	switch opts.color {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return flags.Report(fmt.Errorf("invalid --color argument %q", opts.color), stdout, stderr)
+	}
+
+	style := opts.quoting
+	switch {
+	case style != "":
+	case isTerminal:
+		style = quoteShellEscape
+	default:
+		style = quoteShell
+	}
+	format := defaultFormat
+	format.quote, err = quoterFor(style, opts.hideCtrl)
+	if err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+
+	switch {
+	case opts.si:
+		format.size = humanizeSI
+	case opts.human:
+		format.size = humanizeSize
+	case opts.blocks != "":
+		size, suffix, err := parseBlockSize(opts.blocks)
+		if err != nil {
+			return flags.Report(err, stdout, stderr)
+		}
+		format.blockSize = size
+		format.size = scaledSize(size, suffix)
+	}
+
+	fn := format.printFileNames
+	switch {
+	case opts.json:
+		fn = array.format
+	case opts.ndjson:
+		fn = printFileNDJSON
+	case opts.list:
+		fn = format.printFileList
+	case !opts.single:
+		if opts.across || opts.columns || isTerminal {
+			writer = &columnWriter{out: stdout, width: width, across: opts.across}
+		}
+	}
+
+	ropts := readOptions{
+		unsorted:  opts.unsorted,
+		blockSize: format.blockSize,
+		dots:      opts.all && !opts.almost,
+		directory: opts.dir,
+		archive:   opts.archive,
+		keep:      newNameFilter(opts.all || opts.almost || opts.archive, opts.ignore, opts.hide),
+	}
+	if !opts.json && !opts.ndjson {
+		var decs []decorator
+		colored := useColor(opts.color, isTerminal)
+		if colored {
+			decs = append(decs, parseColors(getenv(env, "LS_COLORS")).colorize)
+		}
+		if opts.classify || opts.slash {
+			decs = append(decs, classify(!opts.classify))
+		}
+		if opts.archive {
+			decs = append(decs, markWhiteout)
+		}
+		if len(decs) > 0 {
+			fn = format.decorate(fn, decs...)
+		}
+		if opts.size {
+			fn = prefixed(fn, format.printBlocks)
+		}
+		if opts.context {
+			fn = prefixed(fn, printContext)
+		}
+		if opts.inode {
+			fn = prefixed(fn, printInode)
+		}
+		if opts.xattrs && opts.list {
+			fn = withXattrs(fn)
+		}
+
+		// names and the directory bit of -p come with the entries
+		ropts.namesOnly = !opts.list && !colored && !opts.classify &&
+			!opts.inode && !opts.size && !opts.archive
+		ropts.total = opts.list || opts.size
+	}
+
+	err = runlsWith(paths, writer, fn, ropts)
+	if opts.json {
+		fmt.Fprint(stdout, array.end())
+	}
+	if err != nil {
+		flags.Diag(stderr, env).Error("", err)
+		return 1
+	}
+	return 0
+}
+
+// getenv returns the value of key in env, a list of key=value pairs.
+// The last one wins, as with os.Getenv.
+func getenv(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return env[i][len(key)+1:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+//go:build linux || dragonfly || openbsd
+// +build linux dragonfly openbsd
+
+package ls
+
+import (
+	"syscall"
+	"time"
+)
+
+func statTimes(statt *syscall.Stat_t) (atime, ctime, btime time.Time) {
+	return timespecToTime(statt.Atim), timespecToTime(statt.Ctim), time.Time{}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package ls
+
+import (
+	"syscall"
+	"time"
+)
+
+func statTimes(statt *syscall.Stat_t) (atime, ctime, btime time.Time) {
+	return timespecToTime(statt.Atimespec),
+		timespecToTime(statt.Ctimespec),
+		timespecToTime(statt.Birthtimespec)
+}
@@ -0,0 +1,1109 @@
+package ls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func setup(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Error(err)
+	}
+
+	createTempFiles(t, dir)
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func createNestedDir(baseDir string) error {
+	return os.Mkdir(filepath.Join(baseDir, "nestedDirName"), 0444)
+}
+
+func checkError(t *testing.T, err error) {
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// testTime is the modification time of the files of setup.
+var testTime = time.Date(2017, 1, 2, 12, 0, 0, 0, time.Local)
+
+func createTempFiles(t *testing.T, dir string) {
+	files := []string{"file with space", "f1.txt", "f2.pdf", "f3"}
+	content := []byte("temporary file's content")
+
+	for _, f := range files {
+		fileName := filepath.Join(dir, f)
+		if err := ioutil.WriteFile(fileName, content, 0444); err != nil {
+			t.Error(err)
+		}
+		checkError(t, os.Chtimes(fileName, testTime, testTime))
+	}
+}
+
+func replaceUserGroup(t *testing.T, raw string) string {
+	var buf bytes.Buffer
+	currentUser, err := user.Current()
+	checkError(t, err)
+
+	group, err := user.LookupGroupId(currentUser.Gid)
+	checkError(t, err)
+
+	data := struct {
+		User  string
+		Group string
+	}{
+		User:  currentUser.Username,
+		Group: group.Name,
+	}
+
+	tpl, err := template.New("list").Parse(raw)
+	if err := tpl.Execute(&buf, data); err != nil {
+		t.Error(err)
+	}
+
+	return buf.String()
+}
+
+func TestListDirWithLongList(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	var buf bytes.Buffer
+	expected := replaceUserGroup(
+		t,
+		"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f1.txt\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f2.pdf\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f3\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 'file with space'\n",
+	)
+
+	files, _ := ioutil.ReadDir(tempDir)
+	ls(files, &buf, defaultFormat.printFileList)
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestListDirFilesWithNewLineSeparator(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	createNestedDir(tempDir)
+
+	var buf bytes.Buffer
+	expected := "f1.txt\n" +
+		"f2.pdf\n" +
+		"f3\n" +
+		"'file with space'\n" +
+		"nestedDirName\n"
+
+	files, _ := ioutil.ReadDir(tempDir)
+	ls(files, &buf, defaultFormat.printFileNames)
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestListAFileWithLongList(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	files, _ := ioutil.ReadDir(tempDir)
+	filepath := filepath.Join(tempDir, files[0].Name())
+
+	var buf bytes.Buffer
+	runls([]string{filepath}, &buf, defaultFormat.printFileList)
+
+	expected := replaceUserGroup(t, "-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f1.txt\n")
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestListFilesWithNewLineSeparator(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	files, _ := ioutil.ReadDir(tempDir)
+
+	var buf bytes.Buffer
+	expected := "f2.pdf\n" +
+		"f3\n"
+
+	files, _ = ioutil.ReadDir(tempDir)
+	ls(files[1:3], &buf, defaultFormat.printFileNames)
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestListFilesAsLongList(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	files, _ := ioutil.ReadDir(tempDir)
+
+	var buf bytes.Buffer
+	expected := replaceUserGroup(
+		t,
+		"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f1.txt\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f2.pdf\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 f3\n"+
+			"-r--r--r-- {{.User}} {{.Group}}     24 Jan  2  2017 'file with space'\n",
+	)
+
+	files, _ = ioutil.ReadDir(tempDir)
+	ls(files, &buf, defaultFormat.printFileList)
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestEmptyStringOnEmptyDirectory(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	createNestedDir(tempDir)
+	files, _ := ioutil.ReadDir(tempDir)
+	filepath := filepath.Join(tempDir, files[4].Name())
+
+	var buf bytes.Buffer
+	runls([]string{filepath}, &buf, defaultFormat.printFileNames)
+
+	expected := replaceUserGroup(t, "")
+
+	output := string(buf.Bytes())
+	if output != expected {
+		t.Errorf("got:\n'%v'\nexpected:\n'%v'\n", output, expected)
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	var m = map[int64]string{
+		0:             "0",
+		1023:          "1023",
+		1024:          "1.00K",
+		1025:          "1.00K",
+		1110000:       "1.06M",
+		8073741824:    "7.52G",
+		1099511627776: "1.00T",
+	}
+
+	for nbytes, expected := range m {
+		if humanized := humanizeSize(nbytes); humanized != expected {
+			t.Errorf("result [%v] not expected for [%v]", humanized, nbytes)
+		}
+	}
+}
+
+func TestListDirAsNDJSON(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, os.Symlink("f1.txt", filepath.Join(tempDir, "link")))
+
+	var buf bytes.Buffer
+	err := runls([]string{tempDir}, &buf, printFileNDJSON)
+	checkError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var entries []fileJSON
+	for _, line := range lines {
+		var entry fileJSON
+		checkError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	f1 := entries[0]
+	if f1.Name != "f1.txt" || f1.Type != "file" || f1.Size != 24 ||
+		f1.Mode != "-r--r--r--" || f1.ModeBits != 0100444 || f1.Nlink != 1 {
+		t.Errorf("unexpected entry: %+v", f1)
+	}
+	if f1.Path != filepath.Join(tempDir, "f1.txt") {
+		t.Errorf("got path %q", f1.Path)
+	}
+
+	link := entries[4]
+	if link.Name != "link" || link.Type != "symlink" || link.LinkTarget != "f1.txt" {
+		t.Errorf("unexpected entry: %+v", link)
+	}
+}
+
+func TestListUnknownOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of a file needs root")
+	}
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, os.Chown(filepath.Join(tempDir, "f2.pdf"), 12345, 54321))
+
+	var buf bytes.Buffer
+	checkError(t, runls([]string{tempDir}, &buf, printFileNDJSON))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	var entry fileJSON
+	checkError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	if entry.Name != "f2.pdf" || entry.User != "12345" || entry.Group != "54321" ||
+		entry.UID != 12345 || entry.GID != 54321 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	buf.Reset()
+	checkError(t, runls([]string{filepath.Join(tempDir, "f2.pdf")}, &buf, defaultFormat.printFileList))
+	if !strings.Contains(buf.String(), " 12345 54321 ") {
+		t.Errorf("expected numeric owners, got %q", buf.String())
+	}
+}
+
+func TestUnixMode(t *testing.T) {
+	tests := []struct {
+		mode     os.FileMode
+		expected uint32
+	}{
+		{0644, 0100644},
+		{os.ModeDir | 0755, 040755},
+		{os.ModeSymlink | 0777, 0120777},
+		{os.ModeDevice | os.ModeCharDevice | 0620, 020620},
+		{os.ModeDevice | 0660, 060660},
+		{os.ModeNamedPipe | 0600, 010600},
+		{os.ModeSocket | 0755, 0140755},
+		{os.ModeDir | os.ModeSticky | 0777, 041777},
+		{os.ModeSetuid | os.ModeSetgid | 0755, 0106755},
+	}
+
+	for _, test := range tests {
+		if bits := unixMode(test.mode); bits != test.expected {
+			t.Errorf("%v: expected %#o, got %#o", test.mode, test.expected, bits)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	tests := []struct {
+		mode     os.FileMode
+		expected string
+	}{
+		{0644, "-rw-r--r--"},
+		{os.ModeDir | 0755, "drwxr-xr-x"},
+		{os.ModeSymlink | 0777, "lrwxrwxrwx"},
+		{os.ModeDevice | os.ModeCharDevice | 0620, "crw--w----"},
+		{os.ModeDevice | 0660, "brw-rw----"},
+		{os.ModeNamedPipe | 0600, "prw-------"},
+		{os.ModeSocket | 0755, "srwxr-xr-x"},
+		{os.ModeDir | os.ModeSticky | 0777, "drwxrwxrwt"},
+		{os.ModeDir | os.ModeSticky | 0770, "drwxrwx--T"},
+		{os.ModeSetuid | os.ModeSetgid | 0755, "-rwsr-sr-x"},
+		{os.ModeSetuid | os.ModeSetgid | 0644, "-rwSr-Sr--"},
+	}
+
+	for _, test := range tests {
+		if str := modeString(test.mode); str != test.expected {
+			t.Errorf("%#o: expected %q, got %q", uint32(test.mode), test.expected, str)
+		}
+	}
+}
+
+func TestListDirAsJSON(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	var array jsonArray
+	var buf bytes.Buffer
+	err := runls([]string{tempDir}, &buf, array.format)
+	checkError(t, err)
+	buf.WriteString(array.end())
+
+	var entries []fileJSON
+	checkError(t, json.Unmarshal(buf.Bytes(), &entries))
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	if entries[3].Name != "file with space" {
+		t.Errorf("got name %q", entries[3].Name)
+	}
+
+	var empty jsonArray
+	if got := empty.end(); got != "[]\n" {
+		t.Errorf("got %q for an empty array", got)
+	}
+}
+
+func TestFormatColumns(t *testing.T) {
+	names := []string{"f1.txt", "f2.pdf", "f3", "'file with space'", "nestedDirName"}
+
+	tests := []struct {
+		width    int
+		across   bool
+		expected string
+	}{
+		{
+			40, false,
+			"f1.txt  f3                 nestedDirName\n" +
+				"f2.pdf  'file with space'\n",
+		},
+		{
+			40, true,
+			"f1.txt             f2.pdf         f3\n" +
+				"'file with space'  nestedDirName\n",
+		},
+		{
+			80, false,
+			"f1.txt  f2.pdf  f3  'file with space'  nestedDirName\n",
+		},
+		{
+			10, false,
+			"f1.txt\nf2.pdf\nf3\n'file with space'\nnestedDirName\n",
+		},
+	}
+
+	for _, test := range tests {
+		output := formatColumns(names, test.width, test.across)
+		if output != test.expected {
+			t.Errorf("width %d, across %v: got:\n%q\nexpected:\n%q\n",
+				test.width, test.across, output, test.expected)
+		}
+	}
+}
+
+func TestListDirInColumns(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	var buf bytes.Buffer
+	writer := &columnWriter{out: &buf, width: 80}
+	err := runls([]string{tempDir, tempDir}, writer, defaultFormat.printFileNames)
+	checkError(t, err)
+
+	line := "f1.txt  f2.pdf  f3  'file with space'\n"
+	if output := buf.String(); output != line+line {
+		t.Errorf("got:\n%q\nexpected:\n%q\n", output, line+line)
+	}
+}
+
+func TestListTotalOutOfColumns(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	var buf bytes.Buffer
+	writer := &columnWriter{out: &buf, width: 80}
+	ropts := readOptions{total: true, blockSize: defaultBlockSize}
+	err := runlsWith([]string{tempDir}, writer, defaultFormat.printFileNames, ropts)
+	checkError(t, err)
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "total ") ||
+		lines[1] != "f1.txt  f2.pdf  f3  'file with space'" {
+		t.Errorf("expected the total line above the columns, got %q", buf.String())
+	}
+}
+
+func TestListNewlineInColumns(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "test")
+	checkError(t, err)
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"a", "nl\nx"} {
+		checkError(t, ioutil.WriteFile(filepath.Join(tempDir, name), nil, 0644))
+	}
+
+	for _, flag := range []string{"-C", "-CU"} {
+		var stdout, stderr bytes.Buffer
+		Run(nil, &stdout, &stderr, []string{"ls", flag, "--quoting-style=literal", tempDir}, nil)
+		// -U lists in directory order
+		if output := stdout.String(); output != "a  nl\nx\n" && output != "nl\nx  a\n" {
+			t.Errorf("%s: expected a and nl\\nx in two cells, got %q", flag, output)
+		}
+	}
+}
+
+func TestParseColors(t *testing.T) {
+	colors := parseColors("di=01;33:*.txt=00;32:*.tar=:bogus")
+
+	if code := colors.types["di"]; code != "01;33" {
+		t.Errorf("got %q for di", code)
+	}
+	if code := colors.types["ln"]; code != "01;36" {
+		t.Errorf("got %q for the default ln", code)
+	}
+	if code := colors.suffixCode("notes.txt"); code != "00;32" {
+		t.Errorf("got %q for *.txt", code)
+	}
+	if code := colors.suffixCode("image.tar"); code != "" {
+		t.Errorf("got %q for an emptied *.tar", code)
+	}
+	if code := colors.suffixCode("image.tar.gz"); code != "01;31" {
+		t.Errorf("got %q for *.gz", code)
+	}
+}
+
+func TestListDirColorizedAndClassified(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	createNestedDir(tempDir)
+	checkError(t, os.Symlink("f1.txt", filepath.Join(tempDir, "link")))
+	checkError(t, os.Symlink("missing", filepath.Join(tempDir, "orphan")))
+	checkError(t, os.Chmod(filepath.Join(tempDir, "f3"), 0555))
+
+	colors := parseColors("*.pdf=00;35")
+	fn := defaultFormat.decorate(defaultFormat.printFileNames, colors.colorize, classify(false))
+
+	var buf bytes.Buffer
+	checkError(t, runls([]string{tempDir}, &buf, fn))
+
+	expected := "f1.txt\n" +
+		"\x1b[00;35mf2.pdf\x1b[0m\n" +
+		"\x1b[01;32mf3\x1b[0m*\n" +
+		"'file with space'\n" +
+		"\x1b[01;36mlink\x1b[0m@\n" +
+		"\x1b[01;34mnestedDirName\x1b[0m/\n" +
+		"\x1b[40;31;01morphan\x1b[0m@\n"
+
+	if output := buf.String(); output != expected {
+		t.Errorf("got:\n%q\nexpected:\n%q\n", output, expected)
+	}
+}
+
+func TestDecorateLongList(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, os.Chmod(filepath.Join(tempDir, "file with space"), 0555))
+	files, _ := ioutil.ReadDir(tempDir)
+
+	var buf bytes.Buffer
+	ls(files[3:], &buf, defaultFormat.decorate(defaultFormat.printFileList, classify(false)))
+
+	expected := replaceUserGroup(t, "-r-xr-xr-x {{.User}} {{.Group}}     24 Jan  2  2017 'file with space'*\n")
+	if output := buf.String(); output != expected {
+		t.Errorf("got:\n%q\nexpected:\n%q\n", output, expected)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		mtime    time.Time
+		expected string
+	}{
+		{time.Date(2017, 5, 31, 9, 5, 0, 0, time.Local), "May 31 09:05"},
+		{time.Date(2017, 1, 2, 12, 0, 0, 0, time.Local), "Jan  2 12:00"},
+		{time.Date(2016, 11, 2, 12, 0, 0, 0, time.Local), "Nov  2  2016"},
+		{time.Date(2017, 6, 2, 12, 0, 0, 0, time.Local), "Jun  2  2017"},
+	}
+
+	for _, test := range tests {
+		if got := formatTime(test.mtime, now); got != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.mtime, test.expected, got)
+		}
+	}
+}
+
+func TestDisplayWidthSkipsEscapes(t *testing.T) {
+	if w := displayWidth("\x1b[01;34m世界\x1b[0m/"); w != 3 {
+		t.Errorf("got width %d", w)
+	}
+}
+
+func TestQuotingStyles(t *testing.T) {
+	names := []string{
+		"plain",
+		"file with space",
+		"it's",
+		"a'b c$",
+		"new\nline",
+		"tab\there",
+		"esc\x1b[31m",
+		"bad\xffutf8",
+		`back\slash "q"`,
+		"日本語",
+	}
+
+	expected := map[string][]string{
+		quoteLiteral: names,
+		quoteShell: {
+			"plain",
+			"'file with space'",
+			`"it's"`,
+			`'a'\''b c$'`,
+			"'new\nline'",
+			"'tab\there'",
+			"'esc\x1b[31m'",
+			"bad\xffutf8",
+			`'back\slash "q"'`,
+			"日本語",
+		},
+		quoteShellEscape: {
+			"plain",
+			"'file with space'",
+			`"it's"`,
+			`'a'\''b c$'`,
+			`'new'$'\n''line'`,
+			`'tab'$'\t''here'`,
+			`'esc'$'\033''[31m'`,
+			`'bad'$'\377''utf8'`,
+			`'back\slash "q"'`,
+			"日本語",
+		},
+		quoteC: {
+			`"plain"`,
+			`"file with space"`,
+			`"it's"`,
+			`"a'b c$"`,
+			`"new\nline"`,
+			`"tab\there"`,
+			`"esc\033[31m"`,
+			`"bad\377utf8"`,
+			`"back\\slash \"q\""`,
+			`"日本語"`,
+		},
+		quoteEscape: {
+			"plain",
+			`file\ with\ space`,
+			"it's",
+			`a'b\ c$`,
+			`new\nline`,
+			`tab\there`,
+			`esc\033[31m`,
+			`bad\377utf8`,
+			`back\\slash\ "q"`,
+			"日本語",
+		},
+	}
+
+	for style, quoted := range expected {
+		quote, err := quoterFor(style, false)
+		checkError(t, err)
+
+		for i, name := range names {
+			if got := quote(name); got != quoted[i] {
+				t.Errorf("%s: got %q for %q, expected %q", style, got, name, quoted[i])
+			}
+		}
+	}
+}
+
+func TestQuotingHidesControlChars(t *testing.T) {
+	quote, err := quoterFor(quoteShell, true)
+	checkError(t, err)
+
+	if got := quote("new\nline\x1b"); got != "'new?line?'" {
+		t.Errorf("got %q", got)
+	}
+
+	for style, expected := range map[string]string{
+		quoteLiteral:     "x?y",
+		quoteShell:       "'x?y'",
+		quoteShellEscape: "'x?y'",
+		quoteC:           `"x?y"`,
+		quoteEscape:      "x?y",
+	} {
+		quote, err := quoterFor(style, true)
+		checkError(t, err)
+		if got := quote("x\x1by"); got != expected {
+			t.Errorf("%s: expected %q, got %q", style, expected, got)
+		}
+	}
+
+	if _, err := quoterFor("bogus", false); err == nil {
+		t.Error("expected an error for an invalid quoting style")
+	}
+}
+
+func TestQuotingLastOneWins(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"ls", "-b"}, quoteEscape},
+		{[]string{"ls", "-b", "--quoting-style=c"}, quoteC},
+		{[]string{"ls", "--quoting-style=c", "-b"}, quoteEscape},
+	}
+
+	for _, test := range tests {
+		_, opts, _, err := parseargs(test.args)
+		checkError(t, err)
+		if opts.quoting != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.args, test.expected, opts.quoting)
+		}
+	}
+}
+
+func createManyFiles(tb testing.TB, n int) (string, func()) {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for i := 0; i < n; i++ {
+		f, err := os.Create(filepath.Join(dir, "file"+strconv.Itoa(i)))
+		if err != nil {
+			os.RemoveAll(dir)
+			tb.Fatal(err)
+		}
+		f.Close()
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestListDirStreamed(t *testing.T) {
+	n := 2*readBatch + 10
+	tempDir, teardown := createManyFiles(t, n)
+	defer teardown()
+
+	for _, ropts := range []readOptions{
+		{unsorted: true},
+		{unsorted: true, namesOnly: true},
+		{namesOnly: true},
+	} {
+		var buf bytes.Buffer
+		err := runlsWith([]string{tempDir}, &buf, defaultFormat.printFileNames, ropts)
+		checkError(t, err)
+
+		names := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(names) != n {
+			t.Fatalf("%+v: expected %d names, got %d", ropts, n, len(names))
+		}
+		if !ropts.unsorted && !sort.StringsAreSorted(names) {
+			t.Errorf("%+v: names are not sorted", ropts)
+		}
+
+		sort.Strings(names)
+		for i := 1; i < n; i++ {
+			if names[i] == names[i-1] {
+				t.Fatalf("%+v: %s listed twice", ropts, names[i])
+			}
+		}
+	}
+}
+
+func TestNamesOnlyKeepsFileType(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	createNestedDir(tempDir)
+
+	var buf bytes.Buffer
+	fn := defaultFormat.decorate(defaultFormat.printFileNames, classify(true))
+	err := runlsWith([]string{tempDir}, &buf, fn, readOptions{namesOnly: true})
+	checkError(t, err)
+
+	if output := buf.String(); !strings.HasSuffix(output, "nestedDirName/\n") {
+		t.Errorf("got:\n%s", output)
+	}
+}
+
+func benchmarkList(b *testing.B, ropts readOptions) {
+	tempDir, teardown := createManyFiles(b, 10000)
+	defer teardown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := runlsWith([]string{tempDir}, ioutil.Discard, defaultFormat.printFileNames, ropts)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListSorted(b *testing.B) {
+	benchmarkList(b, readOptions{})
+}
+
+func BenchmarkListSortedNamesOnly(b *testing.B) {
+	benchmarkList(b, readOptions{namesOnly: true})
+}
+
+func BenchmarkListStreamed(b *testing.B) {
+	benchmarkList(b, readOptions{unsorted: true})
+}
+
+func BenchmarkListStreamedNamesOnly(b *testing.B) {
+	benchmarkList(b, readOptions{unsorted: true, namesOnly: true})
+}
+
+func TestListDeviceNumbers(t *testing.T) {
+	fileInfo, err := os.Stat("/dev/null")
+	if err != nil || fileInfo.Mode()&os.ModeCharDevice == 0 {
+		t.Skip("no /dev/null character device")
+	}
+
+	var buf bytes.Buffer
+	checkError(t, runls([]string{"/dev/null"}, &buf, defaultFormat.printFileList))
+
+	if output := buf.String(); !strings.Contains(output, "   1, 3 ") || !strings.HasSuffix(output, " null\n") {
+		t.Errorf("got:\n%q", output)
+	}
+}
+
+func TestListInodesAndBlocks(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, os.Truncate(filepath.Join(tempDir, "f3"), 0))
+
+	files, _ := ioutil.ReadDir(tempDir)
+	f1, err := inodeNumber(files[0])
+	checkError(t, err)
+	f1Blocks, err := blocks(files[0], defaultBlockSize)
+	checkError(t, err)
+
+	var buf bytes.Buffer
+	fn := prefixed(prefixed(defaultFormat.printFileNames, defaultFormat.printBlocks), printInode)
+	err = runlsWith([]string{tempDir}, &buf, fn, readOptions{total: true, blockSize: defaultBlockSize})
+	checkError(t, err)
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got:\n%s", buf.String())
+	}
+
+	total := "total " + strconv.FormatInt(3*f1Blocks, 10)
+	if lines[0] != total {
+		t.Errorf("got %q, expected %q", lines[0], total)
+	}
+	if expected := fmt.Sprintf("%8d %4d f1.txt", f1, f1Blocks); lines[1] != expected {
+		t.Errorf("got %q, expected %q", lines[1], expected)
+	}
+	if !strings.HasSuffix(lines[3], "    0 f3") {
+		t.Errorf("got %q for an empty file", lines[3])
+	}
+}
+
+func TestDecodeCapability(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected string
+	}{
+		// setcap cap_net_bind_service=ep
+		{"0100000200040000000000000000000000000000", "cap_net_bind_service=ep"},
+		// setcap cap_net_raw,cap_net_admin+p cap_chown+ei, revision 3
+		{"0100000301300000010000000000000000000000e8030000",
+			"cap_chown=eip cap_net_admin,cap_net_raw=ep"},
+		// setcap cap_bpf+i
+		{"0000000200000000000000000000000080000000", "cap_bpf=i"},
+	}
+
+	for _, test := range tests {
+		data, err := hex.DecodeString(test.hex)
+		checkError(t, err)
+
+		got, err := decodeCapability(data)
+		checkError(t, err)
+		if got != test.expected {
+			t.Errorf("got %q, expected %q", got, test.expected)
+		}
+	}
+
+	if _, err := decodeCapability([]byte{0, 0, 0, 9}); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+}
+
+func TestDecodeACL(t *testing.T) {
+	data, err := hex.DecodeString("02000000" +
+		"01000600ffffffff" + // user::rw-
+		"02000700e8030000" + // user:1000:rwx
+		"04000400ffffffff" + // group::r--
+		"10000500ffffffff" + // mask::r-x
+		"20000000ffffffff") // other::---
+	checkError(t, err)
+
+	got, err := decodeACL(data)
+	checkError(t, err)
+
+	expected := "user::rw-,user:1000:rwx,group::r--,mask::r-x,other::---"
+	if got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestListFiltered(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, ioutil.WriteFile(filepath.Join(tempDir, ".hidden"), nil, 0444))
+
+	tests := []struct {
+		ropts    readOptions
+		expected string
+	}{
+		{
+			readOptions{keep: newNameFilter(false, nil, nil)},
+			"f1.txt\nf2.pdf\nf3\n'file with space'\n",
+		},
+		{
+			readOptions{keep: newNameFilter(true, nil, nil)},
+			".hidden\nf1.txt\nf2.pdf\nf3\n'file with space'\n",
+		},
+		{
+			readOptions{dots: true, keep: newNameFilter(true, nil, nil)},
+			".\n..\n.hidden\nf1.txt\nf2.pdf\nf3\n'file with space'\n",
+		},
+		{
+			readOptions{dots: true, unsorted: true, keep: newNameFilter(true, patternList{".*", "f?"}, nil)},
+			"'file with space'\nf1.txt\nf2.pdf\n",
+		},
+		{
+			readOptions{keep: newNameFilter(false, patternList{"*.txt"}, patternList{"*.pdf"})},
+			"f3\n'file with space'\n",
+		},
+		{
+			readOptions{keep: newNameFilter(true, nil, patternList{"*.pdf"})},
+			".hidden\nf1.txt\nf2.pdf\nf3\n'file with space'\n",
+		},
+		{
+			readOptions{directory: true, keep: newNameFilter(false, nil, nil)},
+			filepath.Base(tempDir) + "\n",
+		},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		checkError(t, runlsWith([]string{tempDir}, &buf, defaultFormat.printFileNames, test.ropts))
+
+		output := buf.String()
+		if test.ropts.unsorted {
+			lines := strings.SplitAfter(output, "\n")
+			sort.Strings(lines)
+			output = strings.Join(lines, "")
+		}
+		if output != test.expected {
+			t.Errorf("test index %d: got:\n%q\nexpected:\n%q\n", i, output, test.expected)
+		}
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	var patterns patternList
+	if err := patterns.Set("[a-"); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestHumanizeLargeSizes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		si       bool
+		expected string
+	}{
+		{1125899906842624, false, "1.00P"},
+		{1152921504606846976, false, "1.00E"},
+		{9223372036854775807, false, "8.00E"},
+		{999, true, "999"},
+		{1000, true, "1.00K"},
+		{1500000, true, "1.50M"},
+		{2000000000000000000, true, "2.00E"},
+	}
+
+	for _, test := range tests {
+		humanized := humanizeSize(test.size)
+		if test.si {
+			humanized = humanizeSI(test.size)
+		}
+		if humanized != test.expected {
+			t.Errorf("result [%v] not expected for [%v]", humanized, test.size)
+		}
+	}
+}
+
+func TestParseBlockSize(t *testing.T) {
+	tests := []struct {
+		arg    string
+		size   int64
+		suffix string
+	}{
+		{"512", 512, ""},
+		{"K", 1024, "K"},
+		{"KiB", 1024, "KiB"},
+		{"KB", 1000, "KB"},
+		{"4K", 4096, ""},
+		{"M", 1 << 20, "M"},
+		{"G", 1 << 30, "G"},
+		{"EB", 1000000000000000000, "EB"},
+	}
+
+	for _, test := range tests {
+		size, suffix, err := parseBlockSize(test.arg)
+		checkError(t, err)
+		if size != test.size || suffix != test.suffix {
+			t.Errorf("%s: got %d %q, expected %d %q",
+				test.arg, size, suffix, test.size, test.suffix)
+		}
+	}
+
+	for _, arg := range []string{"", "0", "-1", "Q", "KX", "1.5M", "9E"} {
+		if _, _, err := parseBlockSize(arg); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
+
+func TestListExactAndScaledSizes(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	checkError(t, os.Truncate(filepath.Join(tempDir, "f3"), 1536))
+	checkError(t, os.Chtimes(filepath.Join(tempDir, "f3"), testTime, testTime))
+	files, _ := ioutil.ReadDir(tempDir)
+
+	tests := []struct {
+		format   func(int64) string
+		expected string
+	}{
+		{exactSize, "   1536"},
+		{humanizeSize, "  1.50K"},
+		{scaledSize(1024, "K"), "     2K"},
+	}
+
+	for _, test := range tests {
+		format := defaultFormat
+		format.size = test.format
+
+		var buf bytes.Buffer
+		ls(files[2:3], &buf, format.printFileList)
+
+		expected := replaceUserGroup(t, "-r--r--r-- {{.User}} {{.Group}}"+test.expected+" Jan  2  2017 f3\n")
+		if output := buf.String(); output != expected {
+			t.Errorf("got:\n%q\nexpected:\n%q\n", output, expected)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	var stdout, stderr bytes.Buffer
+	env := []string{"COLUMNS=1000", "LS_COLORS=fi=01"}
+
+	status := Run(nil, &stdout, &stderr, []string{"ls", "-C", "-color=always", tempDir}, env)
+	if status != 0 || stderr.Len() != 0 {
+		t.Fatalf("status %d, stderr %q", status, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "\x1b[01mf1.txt\x1b[0m  ") {
+		t.Errorf("got:\n%q", stdout.String())
+	}
+
+	stdout.Reset()
+	if status := Run(nil, &stdout, &stderr, []string{"ls", "--color=sometimes"}, env); status != 2 {
+		t.Errorf("bad -color: expected status 2, got %d", status)
+	}
+	if status := Run(nil, &stdout, &stderr, []string{"ls", "-nosuchflag"}, env); status != 2 {
+		t.Errorf("bad flag: expected status 2, got %d", status)
+	}
+
+	// the missing path is reported, the others still listed
+	stdout.Reset()
+	stderr.Reset()
+	missing := filepath.Join(tempDir, "missing")
+	if status := Run(nil, &stdout, &stderr, []string{"ls", missing, filepath.Join(tempDir, "f1.txt")}, env); status != 1 {
+		t.Errorf("missing file: expected status 1, got %d", status)
+	}
+	if expected := "ls: " + missing + ": no such file or directory\n"; stderr.String() != expected {
+		t.Errorf("missing file: expected stderr %q, got %q", expected, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "f1.txt") {
+		t.Errorf("missing file: expected f1.txt listed, got %q", stdout.String())
+	}
+
+	stderr.Reset()
+	Run(nil, &stdout, &stderr, []string{"ls", missing}, append(env, "ENZO_DEBUG=1"))
+	if expected := "ls: " + missing + ": no such file or directory (stat, ENOENT)\n"; stderr.String() != expected {
+		t.Errorf("ENZO_DEBUG: expected stderr %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestRunFormatsDontLeak(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	f3 := filepath.Join(tempDir, "f3")
+	checkError(t, os.Truncate(f3, 5000))
+	checkError(t, os.Chtimes(f3, testTime, testTime))
+
+	var stdout, stderr bytes.Buffer
+	for _, args := range [][]string{
+		{"ls", "-l", "--block-size=K", "--quoting-style=c", f3},
+		{"ls", "-l", f3},
+	} {
+		stdout.Reset()
+		if status := Run(nil, &stdout, &stderr, args, nil); status != 0 {
+			t.Fatalf("%v: status %d, stderr %q", args, status, stderr.String())
+		}
+	}
+
+	expected := replaceUserGroup(t, "-r--r--r-- {{.User}} {{.Group}}   5000 Jan  2  2017 f3\n")
+	if stdout.String() != expected {
+		t.Errorf("got %q, expected %q", stdout.String(), expected)
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	env := []string{"COLUMNS=80", "LS_COLORS=", "COLUMNS=120", "COLUMNSX=1"}
+
+	if v := getenv(env, "COLUMNS"); v != "120" {
+		t.Errorf("expected the last COLUMNS, got %q", v)
+	}
+	if v := getenv(env, "LS_COLORS"); v != "" {
+		t.Errorf("expected empty LS_COLORS, got %q", v)
+	}
+	if v := getenv(env, "HOME"); v != "" {
+		t.Errorf("expected no HOME, got %q", v)
+	}
+}
+
+func TestParseargs(t *testing.T) {
+	paths, opts, _, err := parseargs([]string{"ls", "-laI", "*.o", "--hide=*.a", "dir", "-si", "--", "-f"})
+	checkError(t, err)
+
+	if !opts.list || !opts.all || opts.si || !opts.size || !opts.inode {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if fmt.Sprint(opts.ignore, opts.hide) != "[*.o] [*.a]" {
+		t.Errorf("unexpected patterns %v %v", opts.ignore, opts.hide)
+	}
+	if fmt.Sprint(paths) != "[dir -f]" {
+		t.Errorf("unexpected paths %q", paths)
+	}
+
+	// --color takes no separate value, defaulting to always
+	paths, opts, _, err = parseargs([]string{"ls", "--color", ".", "-json", "--si"})
+	checkError(t, err)
+	if opts.color != colorAlways || !opts.json || !opts.si || fmt.Sprint(paths) != "[.]" {
+		t.Errorf("unexpected options %+v and paths %q", opts, paths)
+	}
+	_, opts, _, err = parseargs([]string{"ls", "--color=auto"})
+	checkError(t, err)
+	if opts.color != colorAuto {
+		t.Errorf("expected --color=auto, got %q", opts.color)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := Run(nil, &stdout, &stderr, []string{"ls", "--help"}, nil); status != 0 {
+		t.Errorf("--help: expected status 0, got %d", status)
+	}
+	if !strings.Contains(stdout.String(), "  -a, --all ") {
+		t.Errorf("--help: got %q", stdout.String())
+	}
+}
@@ -0,0 +1,147 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package ls
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+func toStatT(fileInfo os.FileInfo) (*syscall.Stat_t, error) {
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, errors.New("could not get file stat")
+	}
+	return stat, nil
+}
+
+// deviceNumbers decodes the major and minor numbers of the device a
+// character or block special file refers to.
+func deviceNumbers(fileInfo os.FileInfo) (uint64, uint64, bool) {
+	if fileInfo.Mode()&os.ModeDevice == 0 {
+		return 0, 0, false
+	}
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	dev := uint64(statt.Rdev)
+	switch runtime.GOOS {
+	case "darwin":
+		return (dev >> 24) & 0xff, dev & 0xffffff, true
+	case "dragonfly":
+		return (dev >> 8) & 0xff, dev & 0xffff00ff, true
+	case "freebsd":
+		return ((dev >> 32) & 0xffffff00) | ((dev >> 8) & 0xff),
+			((dev >> 24) & 0xff00) | (dev & 0xffff00ff), true
+	case "netbsd":
+		return (dev & 0x000fff00) >> 8,
+			(dev & 0x000000ff) | ((dev & 0xfff00000) >> 12), true
+	case "openbsd":
+		return (dev & 0xff00) >> 8,
+			(dev & 0xff) | ((dev & 0xffff0000) >> 8), true
+	}
+	return ((dev & 0x00000000000fff00) >> 8) | ((dev & 0xfffff00000000000) >> 32),
+		(dev & 0x00000000000000ff) | ((dev & 0x00000ffffff00000) >> 12), true
+}
+
+func inodeNumber(fileInfo os.FileInfo) (uint64, error) {
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(statt.Ino), nil
+}
+
+// allocatedSize returns the bytes of disk actually used by the file,
+// which differs from its size for sparse files and small files.
+func allocatedSize(fileInfo os.FileInfo) (int64, error) {
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return 0, err
+	}
+	return int64(statt.Blocks) * 512, nil
+}
+
+func lookupUser(fileInfo os.FileInfo) (string, error) {
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return "", err
+	}
+
+	// ids without a passwd entry, common in containers, print as
+	// numbers as GNU ls does
+	uid := strconv.FormatUint(uint64(statt.Uid), 10)
+	usr, err := user.LookupId(uid)
+	if err != nil {
+		return uid, nil
+	}
+
+	return usr.Username, nil
+}
+
+func lookupGroup(fileInfo os.FileInfo) (string, error) {
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return "", err
+	}
+
+	gid := strconv.FormatUint(uint64(statt.Gid), 10)
+	group, err := user.LookupGroupId(gid)
+	if err != nil {
+		return gid, nil
+	}
+
+	return group.Name, nil
+}
+
+func fillStat(entry *fileJSON, fileInfo os.FileInfo) error {
+	statt, err := toStatT(fileInfo)
+	if err != nil {
+		return err
+	}
+
+	atime, ctime, btime := statTimes(statt)
+
+	entry.UID = statt.Uid
+	entry.GID = statt.Gid
+	entry.Nlink = uint64(statt.Nlink)
+	entry.Inode = uint64(statt.Ino)
+	entry.Atime = &atime
+	entry.Ctime = &ctime
+	if !btime.IsZero() {
+		entry.Btime = &btime
+	}
+	return nil
+}
+
+func timespecToTime(ts syscall.Timespec) time.Time {
+	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
+}
+
+// terminalWidth returns the number of columns of the terminal at fd and
+// whether fd is a terminal at all.
+func terminalWidth(fd uintptr) (int, bool) {
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		fd,
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&ws)),
+	)
+	if errno != 0 {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
@@ -0,0 +1,36 @@
+//go:build windows
+// +build windows
+
+package ls
+
+import (
+	"os"
+)
+
+func lookupUser(fileinfo os.FileInfo) (string, error) {
+	return "unknown", nil
+}
+
+func lookupGroup(fileinfo os.FileInfo) (string, error) {
+	return "unknown", nil
+}
+
+func fillStat(entry *fileJSON, fileInfo os.FileInfo) error {
+	return nil
+}
+
+func terminalWidth(fd uintptr) (int, bool) {
+	return 0, false
+}
+
+func deviceNumbers(fileInfo os.FileInfo) (uint64, uint64, bool) {
+	return 0, 0, false
+}
+
+func inodeNumber(fileInfo os.FileInfo) (uint64, error) {
+	return 0, nil
+}
+
+func allocatedSize(fileInfo os.FileInfo) (int64, error) {
+	return fileInfo.Size(), nil
+}
@@ -0,0 +1,197 @@
+package ls
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// quoting styles, named as in GNU ls --quoting-style.
+const (
+	quoteLiteral     = "literal"
+	quoteShell       = "shell"
+	quoteShellEscape = "shell-escape"
+	quoteC           = "c"
+	quoteEscape      = "escape"
+)
+
+// shellSpecial are the characters that make a name need quoting when
+// pasted into a shell.
+const shellSpecial = " \t\n\"#$&'()*;<=>?[\\]^`{|}~!"
+
+type quoter func(name string) string
+
+// quoterFor returns the quoter of style. With hideControl unprintable
+// characters become ? before any quoting.
+func quoterFor(style string, hideControl bool) (quoter, error) {
+	var fn quoter
+
+	switch style {
+	case quoteLiteral:
+		fn = func(name string) string { return name }
+	case quoteShell:
+		fn = shellQuote
+	case quoteShellEscape:
+		fn = shellEscapeQuote
+	case quoteC:
+		fn = cQuote
+	case quoteEscape:
+		fn = escapeQuote
+	default:
+		return nil, fmt.Errorf("invalid quoting style %q", style)
+	}
+
+	if !hideControl {
+		return fn, nil
+	}
+	return func(name string) string {
+		return fn(hideControlChars(name))
+	}, nil
+}
+
+// nextRune decodes the first rune of s, reporting whether it can be
+// printed as is.
+func nextRune(s string) (rune, int, bool) {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && size <= 1 {
+		return r, size, false
+	}
+	return r, size, unicode.IsPrint(r)
+}
+
+func isPrintable(name string) bool {
+	for len(name) > 0 {
+		_, size, ok := nextRune(name)
+		if !ok {
+			return false
+		}
+		name = name[size:]
+	}
+	return true
+}
+
+func hideControlChars(name string) string {
+	var b strings.Builder
+
+	for len(name) > 0 {
+		_, size, ok := nextRune(name)
+		if ok {
+			b.WriteString(name[:size])
+		} else {
+			b.WriteByte('?')
+		}
+		name = name[size:]
+	}
+	return b.String()
+}
+
+// shellQuote quotes name only if a shell would otherwise split or
+// expand it. Control characters are printed raw.
+func shellQuote(name string) string {
+	if name != "" && !strings.ContainsAny(name, shellSpecial) {
+		return name
+	}
+	if strings.Contains(name, "'") && !strings.ContainsAny(name, "\"$`\\!") {
+		return `"` + name + `"`
+	}
+	return "'" + strings.Replace(name, "'", `'\''`, -1) + "'"
+}
+
+// shellEscapeQuote is shellQuote, except that unprintable characters
+// are written as $'...' ANSI-C strings so the result is always safe to
+// print and paste.
+func shellEscapeQuote(name string) string {
+	if isPrintable(name) {
+		return shellQuote(name)
+	}
+
+	var b strings.Builder
+	quoted := false
+
+	for len(name) > 0 {
+		r, size, ok := nextRune(name)
+		switch {
+		case !ok:
+			if quoted {
+				b.WriteByte('\'')
+				quoted = false
+			}
+			b.WriteString("$'" + escapeRune(name[:size]) + "'")
+		case r == '\'':
+			if quoted {
+				b.WriteByte('\'')
+				quoted = false
+			}
+			b.WriteString(`\'`)
+		default:
+			if !quoted {
+				b.WriteByte('\'')
+				quoted = true
+			}
+			b.WriteString(name[:size])
+		}
+		name = name[size:]
+	}
+	if quoted {
+		b.WriteByte('\'')
+	}
+	return b.String()
+}
+
+// escapeRune returns the C escape sequence of the unprintable rune
+// encoded in s.
+func escapeRune(s string) string {
+	switch s {
+	case "\a":
+		return `\a`
+	case "\b":
+		return `\b`
+	case "\f":
+		return `\f`
+	case "\n":
+		return `\n`
+	case "\r":
+		return `\r`
+	case "\t":
+		return `\t`
+	case "\v":
+		return `\v`
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		fmt.Fprintf(&b, `\%03o`, s[i])
+	}
+	return b.String()
+}
+
+func cEscape(name string, escapeSpace bool, escapeQuote bool) string {
+	var b strings.Builder
+
+	for len(name) > 0 {
+		r, size, ok := nextRune(name)
+		switch {
+		case !ok:
+			b.WriteString(escapeRune(name[:size]))
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"' && escapeQuote:
+			b.WriteString(`\"`)
+		case r == ' ' && escapeSpace:
+			b.WriteString(`\ `)
+		default:
+			b.WriteString(name[:size])
+		}
+		name = name[size:]
+	}
+	return b.String()
+}
+
+func cQuote(name string) string {
+	return `"` + cEscape(name, false, true) + `"`
+}
+
+func escapeQuote(name string) string {
+	return cEscape(name, true, false)
+}
@@ -0,0 +1,176 @@
+package ls
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// readBatch is how many entries are read per getdents round when
+// streaming a directory.
+const readBatch = 1024
+
+// readOptions controls how runlsWith reads directories.
+type readOptions struct {
+	// unsorted streams entries in directory order, printing each batch
+	// as soon as it is read.
+	unsorted bool
+	// namesOnly skips the lstat of every entry, for formatters that use
+	// nothing but the name and the type bits of the mode.
+	namesOnly bool
+	// total prints the blocks used by a directory before its sorted
+	// entries. Streamed directories have no total line.
+	total bool
+	// blockSize is the unit of the total line.
+	blockSize int64
+	// dots lists the . and .. entries of every directory.
+	dots bool
+	// directory lists directories themselves instead of their contents.
+	directory bool
+	// archive lists the members of the tar archives at the paths
+	// instead of the paths themselves.
+	archive bool
+	// keep, if set, filters directory entries by name before they are
+	// stat'ed or formatted.
+	keep nameFilter
+}
+
+// dirEntryInfo is the os.FileInfo of a directory entry that was never
+// stat'ed: only its name and file type are known.
+type dirEntryInfo struct {
+	os.DirEntry
+}
+
+func (d dirEntryInfo) Size() int64        { return 0 }
+func (d dirEntryInfo) Mode() os.FileMode  { return d.Type() }
+func (d dirEntryInfo) ModTime() time.Time { return time.Time{} }
+func (d dirEntryInfo) Sys() interface{}   { return nil }
+
+// namedInfo renames an os.FileInfo, for the . and .. entries.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (n namedInfo) Name() string { return n.name }
+
+func (ropts readOptions) keeps(name string) bool {
+	return ropts.keep == nil || ropts.keep(name)
+}
+
+func dotEntries(dir string, ropts readOptions) ([]os.FileInfo, error) {
+	var files []os.FileInfo
+
+	for _, name := range []string{".", ".."} {
+		if !ropts.keeps(name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fileEntry{namedInfo{info, name}, path})
+	}
+
+	return files, nil
+}
+
+// entryInfos converts the entries read from dir, dropping the filtered
+// ones and the ones removed before they could be stat'ed.
+func entryInfos(dir string, entries []os.DirEntry, ropts readOptions) ([]os.FileInfo, error) {
+	files := make([]os.FileInfo, 0, len(entries))
+
+	for _, entry := range entries {
+		if !ropts.keeps(entry.Name()) {
+			continue
+		}
+
+		var info os.FileInfo = dirEntryInfo{entry}
+		if !ropts.namesOnly {
+			var err error
+			info, err = entry.Info()
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, fileEntry{info, filepath.Join(dir, entry.Name())})
+	}
+
+	return files, nil
+}
+
+func readDir(path string, ropts readOptions) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := entryInfos(path, entries, ropts)
+	if err != nil || !ropts.dots {
+		return files, err
+	}
+
+	dots, err := dotEntries(path, ropts)
+	if err != nil {
+		return nil, err
+	}
+	return append(dots, files...), nil
+}
+
+// streamDir lists path in directory order, readBatch entries at a time,
+// so memory stays bounded however big the directory is.
+func streamDir(path string, writer io.Writer, fn formatter, ropts readOptions) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	if ropts.dots {
+		dots, err := dotEntries(path, ropts)
+		if err != nil {
+			return err
+		}
+		if err := ls(dots, writer, fn); err != nil {
+			return err
+		}
+	}
+
+	// a columnWriter takes an entry per write and buffers them anyway
+	var buf bytes.Buffer
+	out := io.Writer(&buf)
+	if _, ok := writer.(*columnWriter); ok {
+		out = writer
+	}
+	for {
+		entries, err := dir.ReadDir(readBatch)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		files, ferr := entryInfos(path, entries, ropts)
+		if ferr != nil {
+			return ferr
+		}
+
+		buf.Reset()
+		if lerr := ls(files, out, fn); lerr != nil {
+			return lerr
+		}
+		if buf.Len() > 0 {
+			if _, werr := writer.Write(buf.Bytes()); werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF || len(entries) == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ls
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// unitPrefixes are the size prefixes in increasing order of magnitude.
+const unitPrefixes = "KMGTPE"
+
+// defaultBlockSize is the unit of the -s block counts and of the total
+// line without --block-size.
+const defaultBlockSize = 1024
+
+func exactSize(size int64) string {
+	return strconv.FormatInt(size, 10)
+}
+
+func humanize(size int64, base float64) string {
+	sz := float64(size)
+	for i := len(unitPrefixes); i > 0; i-- {
+		if scale := math.Pow(base, float64(i)); sz >= scale {
+			return fmt.Sprintf("%.2f%c", sz/scale, unitPrefixes[i-1])
+		}
+	}
+
+	return fmt.Sprintf("%d", size)
+}
+
+func humanizeSize(size int64) string {
+	return humanize(size, 1024)
+}
+
+func humanizeSI(size int64) string {
+	return humanize(size, 1000)
+}
+
+// scaledSize prints sizes in units of unit bytes, rounded up, followed
+// by suffix.
+func scaledSize(unit int64, suffix string) func(int64) string {
+	return func(size int64) string {
+		return strconv.FormatInt((size+unit-1)/unit, 10) + suffix
+	}
+}
+
+// parseBlockSize parses a --block-size argument: an optional integer
+// followed by an optional unit such as K, KiB (powers of 1024) or KB
+// (powers of 1000). A unit given without a number is returned to be
+// printed after sizes, as GNU ls does.
+func parseBlockSize(arg string) (int64, string, error) {
+	if arg == "" {
+		return 0, "", fmt.Errorf("invalid block size %q", arg)
+	}
+
+	digits := strings.TrimRight(arg, unitPrefixes+"iB")
+	suffix := arg[len(digits):]
+
+	size := int64(1)
+	if digits != "" {
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, "", fmt.Errorf("invalid block size %q", arg)
+		}
+		size = n
+	}
+	if suffix == "" {
+		return size, "", nil
+	}
+
+	prefix := strings.IndexByte(unitPrefixes, suffix[0])
+	base := int64(1024)
+	switch suffix[1:] {
+	case "", "iB":
+	case "B":
+		base = 1000
+	default:
+		prefix = -1
+	}
+	if prefix < 0 {
+		return 0, "", fmt.Errorf("invalid block size %q", arg)
+	}
+
+	for i := 0; i <= prefix; i++ {
+		if size > math.MaxInt64/base {
+			return 0, "", fmt.Errorf("block size %q too large", arg)
+		}
+		size *= base
+	}
+	if digits != "" {
+		suffix = ""
+	}
+	return size, suffix, nil
+}
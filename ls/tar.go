@@ -0,0 +1,152 @@
+package ls
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whiteout prefixes and opaque markers of overlay filesystem layers, as
+// in the OCI image spec.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// tarEntry is the os.FileInfo of an archive member, named by its full
+// path inside the archive. Sys returns its *tar.Header.
+type tarEntry struct {
+	os.FileInfo
+	name string
+}
+
+func (e tarEntry) Name() string { return e.name }
+
+func headerOf(fileInfo os.FileInfo) (*tar.Header, bool) {
+	header, ok := fileInfo.Sys().(*tar.Header)
+	return header, ok
+}
+
+func headerOwner(header *tar.Header) (string, string, error) {
+	userName, groupName := header.Uname, header.Gname
+	if userName == "" {
+		userName = strconv.Itoa(header.Uid)
+	}
+	if groupName == "" {
+		groupName = strconv.Itoa(header.Gid)
+	}
+	return userName, groupName, nil
+}
+
+func fillHeader(entry *fileJSON, header *tar.Header) {
+	entry.UID = uint32(header.Uid)
+	entry.GID = uint32(header.Gid)
+	entry.Whiteout = whiteoutKind(entry.Name)
+	if header.Typeflag == tar.TypeLink {
+		entry.LinkTarget = header.Linkname
+	}
+
+	for _, t := range []struct {
+		time time.Time
+		dest **time.Time
+	}{
+		{header.AccessTime, &entry.Atime},
+		{header.ChangeTime, &entry.Ctime},
+	} {
+		if !t.time.IsZero() {
+			tm := t.time
+			*t.dest = &tm
+		}
+	}
+}
+
+// whiteoutKind tells if the member called name deletes a file of the
+// lower layers ("whiteout") or hides a whole directory ("opaque").
+func whiteoutKind(name string) string {
+	switch base := path.Base(name); {
+	case base == whiteoutOpaque:
+		return "opaque"
+	case strings.HasPrefix(base, whiteoutPrefix):
+		return "whiteout"
+	}
+	return ""
+}
+
+func markWhiteout(fileInfo os.FileInfo, name string) string {
+	switch whiteoutKind(fileInfo.Name()) {
+	case "opaque":
+		return name + " [opaque]"
+	case "whiteout":
+		return name + " [whiteout]"
+	}
+	return name
+}
+
+// openArchive returns a reader of the tar archive called name, which
+// may be gzip compressed as most image layers are.
+func openArchive(name string) (*tar.Reader, io.Closer, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	magic, err := reader.Peek(len(gzipMagic))
+	if err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), file, nil
+	}
+
+	return tar.NewReader(reader), file, nil
+}
+
+// memberName cleans the name of an archive member, which is usually
+// relative to ./ or, rarely, absolute.
+func memberName(name string) string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+// listArchive lists every member of the tar archive called name as it
+// is read, without extracting anything.
+func listArchive(name string, writer io.Writer, fn formatter, ropts readOptions) error {
+	archive, closer, err := openArchive(name)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := tarEntry{header.FileInfo(), memberName(header.Name)}
+		if !ropts.keeps(path.Base(entry.name)) {
+			continue
+		}
+		if err := ls([]os.FileInfo{entry}, writer, fn); err != nil {
+			return err
+		}
+	}
+}
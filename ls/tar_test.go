@@ -0,0 +1,136 @@
+package ls
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeLayer(t *testing.T, compress bool) (string, func()) {
+	dir, err := ioutil.TempDir("", "test")
+	checkError(t, err)
+
+	f, err := os.Create(filepath.Join(dir, "layer.tar"))
+	checkError(t, err)
+	defer f.Close()
+
+	var out io.Writer = f
+	if compress {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		out = gz
+	}
+
+	mtime := time.Date(2017, 1, 2, 12, 0, 0, 0, time.UTC)
+	capability := "\x01\x00\x00\x02\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"
+	headers := []*tar.Header{
+		{Name: "./etc/", Typeflag: tar.TypeDir, Mode: 0755, Uname: "root", Gname: "root"},
+		{Name: "./etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 5, Uid: 1000, Gid: 1000},
+		{Name: "./etc/.wh.shadow", Typeflag: tar.TypeReg, Mode: 0, Uname: "root", Gname: "root"},
+		{Name: "./var/.wh..wh..opq", Typeflag: tar.TypeReg, Mode: 0, Uname: "root", Gname: "root"},
+		{Name: "./bin/sh", Typeflag: tar.TypeSymlink, Linkname: "busybox", Mode: 0777, Uname: "root", Gname: "root"},
+		{Name: "./bin/ash", Typeflag: tar.TypeLink, Linkname: "bin/busybox", Mode: 0755, Uname: "root", Gname: "root"},
+		{Name: "./dev/null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3, Uname: "root", Gname: "root"},
+		{Name: "./bin/ping", Typeflag: tar.TypeReg, Mode: 0755, Uname: "root", Gname: "root",
+			PAXRecords: map[string]string{paxXattr + xattrCapability: capability}},
+	}
+
+	tw := tar.NewWriter(out)
+	for _, header := range headers {
+		header.ModTime = mtime
+		if header.PAXRecords != nil {
+			header.Format = tar.FormatPAX
+		}
+		checkError(t, tw.WriteHeader(header))
+		if header.Size > 0 {
+			_, err := tw.Write([]byte("root\n"))
+			checkError(t, err)
+		}
+	}
+	checkError(t, tw.Close())
+
+	return f.Name(), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestListArchive(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		layer, teardown := writeLayer(t, compress)
+		defer teardown()
+
+		var buf bytes.Buffer
+		fn := withXattrs(defaultFormat.decorate(defaultFormat.printFileList, markWhiteout))
+		err := runlsWith([]string{layer}, &buf, fn, readOptions{archive: true})
+		checkError(t, err)
+
+		expected := "drwxr-xr-x root root      0 Jan  2  2017 etc\n" +
+			"-rw-r--r-- 1000 1000      5 Jan  2  2017 etc/passwd\n" +
+			"---------- root root      0 Jan  2  2017 etc/.wh.shadow [whiteout]\n" +
+			"---------- root root      0 Jan  2  2017 var/.wh..wh..opq [opaque]\n" +
+			"lrwxrwxrwx root root      0 Jan  2  2017 bin/sh -> busybox\n" +
+			"-rwxr-xr-x root root      0 Jan  2  2017 bin/ash link to bin/busybox\n" +
+			"crw-rw-rw- root root   1, 3 Jan  2  2017 dev/null\n" +
+			"-rwxr-xr-x@ root root      0 Jan  2  2017 bin/ping\n" +
+			"\tsecurity.capability\tcap_net_bind_service=ep\n"
+
+		if output := buf.String(); output != expected {
+			t.Errorf("compressed %v: got:\n%s\nexpected:\n%s\n", compress, output, expected)
+		}
+	}
+}
+
+func TestListNotAnArchive(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+	f1 := filepath.Join(tempDir, "f1.txt")
+	missing := filepath.Join(tempDir, "missing")
+
+	var stdout, stderr bytes.Buffer
+	if status := Run(nil, &stdout, &stderr, []string{"ls", "--tar", f1, missing}, nil); status != 1 {
+		t.Errorf("expected status 1, got %d", status)
+	}
+	expected := "ls: " + f1 + ": unexpected EOF\n" +
+		"ls: " + missing + ": no such file or directory\n"
+	if stderr.String() != expected {
+		t.Errorf("got stderr %q, expected %q", stderr.String(), expected)
+	}
+}
+
+func TestListArchiveAsNDJSON(t *testing.T) {
+	layer, teardown := writeLayer(t, true)
+	defer teardown()
+
+	var buf bytes.Buffer
+	err := runlsWith([]string{layer}, &buf, printFileNDJSON, readOptions{archive: true})
+	checkError(t, err)
+
+	decoder := json.NewDecoder(&buf)
+	var entries []fileJSON
+	for decoder.More() {
+		var entry fileJSON
+		checkError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 8 {
+		t.Fatalf("expected 8 entries, got %d", len(entries))
+	}
+	mtime := time.Date(2017, 1, 2, 12, 0, 0, 0, time.UTC)
+	if e := entries[1]; e.Name != "etc/passwd" || e.UID != 1000 || e.Size != 5 || !e.Mtime.Equal(mtime) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e := entries[2]; e.Whiteout != "whiteout" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e := entries[4]; e.Type != "symlink" || e.LinkTarget != "busybox" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
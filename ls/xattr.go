@@ -0,0 +1,252 @@
+package ls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	xattrCapability = "security.capability"
+	xattrSELinux    = "security.selinux"
+	xattrACLAccess  = "system.posix_acl_access"
+	xattrACLDefault = "system.posix_acl_default"
+
+	// paxXattr prefixes the PAX records holding extended attributes.
+	paxXattr = "SCHILY.xattr."
+)
+
+// capNames are the capability names of linux/capability.h, indexed by
+// capability number.
+var capNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+func capName(n uint) string {
+	if int(n) < len(capNames) {
+		return capNames[n]
+	}
+	return "cap_" + strconv.Itoa(int(n))
+}
+
+// decodeCapability renders a vfs_cap_data value the way getcap does,
+// e.g. "cap_net_bind_service=ep".
+func decodeCapability(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", fmt.Errorf("capability too short: %d bytes", len(data))
+	}
+
+	magic := binary.LittleEndian.Uint32(data)
+	effective := magic&0x000001 != 0
+
+	words := 2
+	switch magic & 0xff000000 {
+	case 0x01000000:
+		words = 1
+	case 0x02000000, 0x03000000:
+	default:
+		return "", fmt.Errorf("unknown capability revision %#x", magic&0xff000000)
+	}
+	if len(data) < 4+8*words {
+		return "", fmt.Errorf("capability too short: %d bytes", len(data))
+	}
+
+	var permitted, inheritable uint64
+	for i := 0; i < words; i++ {
+		permitted |= uint64(binary.LittleEndian.Uint32(data[4+8*i:])) << (32 * uint(i))
+		inheritable |= uint64(binary.LittleEndian.Uint32(data[8+8*i:])) << (32 * uint(i))
+	}
+
+	var order []string
+	groups := make(map[string][]string)
+	for n := uint(0); n < 64; n++ {
+		flags := ""
+		if effective && permitted&(1<<n) != 0 {
+			flags += "e"
+		}
+		if inheritable&(1<<n) != 0 {
+			flags += "i"
+		}
+		if permitted&(1<<n) != 0 {
+			flags += "p"
+		}
+		if flags == "" {
+			continue
+		}
+		if _, ok := groups[flags]; !ok {
+			order = append(order, flags)
+		}
+		groups[flags] = append(groups[flags], capName(n))
+	}
+
+	clauses := make([]string, 0, len(order))
+	for _, flags := range order {
+		clauses = append(clauses, strings.Join(groups[flags], ",")+"="+flags)
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// decodeACL renders a posix_acl_xattr value in the short text form of
+// getfacl, e.g. "user::rw-,group::r--,other::r--".
+func decodeACL(data []byte) (string, error) {
+	if len(data) < 4 || binary.LittleEndian.Uint32(data) != 2 {
+		return "", fmt.Errorf("unknown ACL version")
+	}
+
+	var entries []string
+	for data = data[4:]; len(data) >= 8; data = data[8:] {
+		tag := binary.LittleEndian.Uint16(data)
+		perm := binary.LittleEndian.Uint16(data[2:])
+		id := strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data[4:])), 10)
+
+		var entry string
+		switch tag {
+		case 0x01:
+			entry = "user:"
+		case 0x02:
+			entry = "user:" + id
+		case 0x04:
+			entry = "group:"
+		case 0x08:
+			entry = "group:" + id
+		case 0x10:
+			entry = "mask:"
+		case 0x20:
+			entry = "other:"
+		default:
+			return "", fmt.Errorf("unknown ACL tag %#x", tag)
+		}
+
+		rwx := []byte("---")
+		for i, c := range "rwx" {
+			if perm&(4>>uint(i)) != 0 {
+				rwx[i] = byte(c)
+			}
+		}
+		entries = append(entries, entry+":"+string(rwx))
+	}
+
+	return strings.Join(entries, ","), nil
+}
+
+// securityMarker returns "+" when the file has an ACL and "@" when it
+// has other extended attributes. SELinux labels are left out, they are
+// on every file of a labeled system and shown by -Z instead.
+func securityMarker(fileInfo os.FileInfo) string {
+	names, err := fileXattrs(fileInfo)
+	if err != nil {
+		return ""
+	}
+
+	marker := ""
+	for _, name := range names {
+		switch name {
+		case xattrACLAccess, xattrACLDefault:
+			return "+"
+		case xattrSELinux:
+		default:
+			marker = "@"
+		}
+	}
+	return marker
+}
+
+// fileXattrs lists the extended attributes of a file, or those
+// recorded in the PAX headers of an archive member.
+func fileXattrs(fileInfo os.FileInfo) ([]string, error) {
+	header, ok := headerOf(fileInfo)
+	if !ok {
+		return listXattrs(entryPath(fileInfo))
+	}
+
+	var names []string
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, paxXattr) {
+			names = append(names, strings.TrimPrefix(key, paxXattr))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func fileXattr(fileInfo os.FileInfo, name string) ([]byte, error) {
+	if header, ok := headerOf(fileInfo); ok {
+		value, ok := header.PAXRecords[paxXattr+name]
+		if !ok {
+			return nil, nil
+		}
+		return []byte(value), nil
+	}
+	return getXattr(entryPath(fileInfo), name)
+}
+
+func printContext(fileInfo os.FileInfo) (string, error) {
+	label, err := fileXattr(fileInfo, xattrSELinux)
+	if err != nil || len(label) == 0 {
+		return "? ", nil
+	}
+	return strings.TrimRight(string(label), "\x00") + " ", nil
+}
+
+func describeXattr(name string, value []byte) string {
+	var (
+		txt string
+		err error
+	)
+
+	switch name {
+	case xattrCapability:
+		txt, err = decodeCapability(value)
+	case xattrACLAccess, xattrACLDefault:
+		txt, err = decodeACL(value)
+	default:
+		value = []byte(strings.TrimRight(string(value), "\x00"))
+		if utf8.Valid(value) && isPrintable(string(value)) {
+			return strconv.Quote(string(value))
+		}
+		return fmt.Sprintf("<%d bytes>", len(value))
+	}
+
+	if err != nil {
+		return fmt.Sprintf("<%d bytes: %s>", len(value), err)
+	}
+	return txt
+}
+
+// withXattrs wraps fn so every file is followed by one indented line
+// per extended attribute, with capabilities and ACLs decoded.
+func withXattrs(fn formatter) formatter {
+	return func(fileInfo os.FileInfo) (string, error) {
+		txt, err := fn(fileInfo)
+		if err != nil {
+			return "", err
+		}
+
+		names, err := fileXattrs(fileInfo)
+		if err != nil {
+			return txt, nil
+		}
+		for _, name := range names {
+			value, err := fileXattr(fileInfo, name)
+			if err != nil {
+				continue
+			}
+			txt += fmt.Sprintf("\t%s\t%s\n", name, describeXattr(name, value))
+		}
+		return txt, nil
+	}
+}
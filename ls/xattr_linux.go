@@ -0,0 +1,100 @@
+package ls
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// xattrCall runs a *xattr syscall that fills dest, first asking for the
+// size needed and retrying if the attribute grows in between.
+func xattrCall(call func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := call(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+
+		dest := make([]byte, size)
+		n, err := call(dest)
+		if err == syscall.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return dest[:n], nil
+	}
+}
+
+func bufferPtr(dest []byte) uintptr {
+	if len(dest) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(&dest[0]))
+}
+
+// listXattrs returns the names of the extended attributes of path,
+// without following symlinks.
+func listXattrs(path string) ([]string, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := xattrCall(func(dest []byte) (int, error) {
+		n, _, errno := syscall.Syscall(
+			syscall.SYS_LLISTXATTR,
+			uintptr(unsafe.Pointer(p)),
+			bufferPtr(dest),
+			uintptr(len(dest)),
+		)
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(n), nil
+	})
+	if err == syscall.ENOTSUP {
+		return nil, nil
+	}
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+
+	return strings.Split(strings.TrimSuffix(string(list), "\x00"), "\x00"), nil
+}
+
+// getXattr returns the value of the extended attribute name of path,
+// or nil if it isn't set.
+func getXattr(path, name string) ([]byte, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := xattrCall(func(dest []byte) (int, error) {
+		size, _, errno := syscall.Syscall6(
+			syscall.SYS_LGETXATTR,
+			uintptr(unsafe.Pointer(p)),
+			uintptr(unsafe.Pointer(n)),
+			bufferPtr(dest),
+			uintptr(len(dest)),
+			0, 0,
+		)
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(size), nil
+	})
+	if err == syscall.ENODATA || err == syscall.ENOTSUP {
+		return nil, nil
+	}
+	return value, err
+}
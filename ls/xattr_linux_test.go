@@ -0,0 +1,34 @@
+package ls
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestListExtendedAttributes(t *testing.T) {
+	tempDir, teardown := setup(t)
+	defer teardown()
+
+	path := filepath.Join(tempDir, "f1.txt")
+	if err := syscall.Setxattr(path, "user.origin", []byte("layer0"), 0); err != nil {
+		t.Skipf("user xattrs not supported: %s", err)
+	}
+
+	var buf bytes.Buffer
+	fn := withXattrs(defaultFormat.printFileList)
+	checkError(t, runls([]string{tempDir}, &buf, fn))
+
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[0], "-r--r--r--@ ") || !strings.HasSuffix(lines[0], " f1.txt") {
+		t.Errorf("got %q", lines[0])
+	}
+	if lines[1] != "\tuser.origin\t\"layer0\"" {
+		t.Errorf("got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "-r--r--r-- ") {
+		t.Errorf("got %q for a file without xattrs", lines[2])
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package ls
+
+func listXattrs(path string) ([]string, error) {
+	return nil, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package uniq
+
+import (
+	"io"
+	"os"
+)
+
+// terminatedReader appends delim to the content of r if it doesn't end
+// with it, so concatenated inputs never merge a last and a first record.
+type terminatedReader struct {
+	r       io.Reader
+	delim   byte
+	last    byte
+	seen    bool
+	eof     bool
+	pending bool
+}
+
+func (t *terminatedReader) Read(p []byte) (int, error) {
+	if t.eof {
+		if t.pending && len(p) > 0 {
+			p[0] = t.delim
+			t.pending = false
+			return 1, nil
+		}
+		return 0, io.EOF
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.last = p[n-1]
+		t.seen = true
+	}
+	if err == io.EOF {
+		t.eof = true
+		t.pending = t.seen && t.last != t.delim
+		if n == 0 {
+			return t.Read(p)
+		}
+		return n, nil
+	}
+	return n, err
+}
+
+// openInputs opens the named inputs, "-" being stdin, and returns them
+// as a single stream of records. No names means stdin.
+func openInputs(names []string, delim byte, stdin io.Reader) (io.Reader, func(), error) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	readers := make([]io.Reader, 0, len(names))
+	for _, name := range names {
+		r := stdin
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			files = append(files, f)
+			r = f
+		}
+		readers = append(readers, &terminatedReader{r: r, delim: delim})
+	}
+
+	return io.MultiReader(readers...), closeAll, nil
+}
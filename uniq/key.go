@@ -0,0 +1,133 @@
+package uniq
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// What to do with the lines a -re pattern doesn't match.
+const (
+	noMatchPass  = "pass"
+	noMatchSkip  = "skip"
+	noMatchCount = "count"
+)
+
+// keyed reports whether lines are compared by something other than
+// their whole text.
+func (opts options) keyed() bool {
+	return opts.skipFields > 0 || opts.skipChars > 0 || opts.checkChars > 0 ||
+		opts.foldCase || opts.trimTrailing || opts.pattern != nil || opts.ignoreCR
+}
+
+// compilePattern compiles the -re pattern and resolves the -group to
+// compare, a number or a name. Without -group the first group is used,
+// or the whole match if there are no groups.
+func compilePattern(expr, group string) (*regexp.Regexp, int, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if group == "" {
+		if pattern.NumSubexp() > 0 {
+			return pattern, 1, nil
+		}
+		return pattern, 0, nil
+	}
+
+	index, err := strconv.Atoi(group)
+	if err != nil {
+		index = pattern.SubexpIndex(group)
+	}
+	if index < 0 || index > pattern.NumSubexp() {
+		return nil, 0, errors.New("no group " + group + " in " + expr)
+	}
+	return pattern, index, nil
+}
+
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// skipFields drops the first n fields of s. As in POSIX uniq, a field
+// is a run of blanks followed by a run of non-blanks.
+func skipFields(s string, n int) string {
+	for ; n > 0 && s != ""; n-- {
+		s = strings.TrimLeftFunc(s, isBlank)
+		if i := strings.IndexFunc(s, isBlank); i >= 0 {
+			s = s[i:]
+		} else {
+			s = ""
+		}
+	}
+	return s
+}
+
+// skipChars drops the first n characters of s.
+func skipChars(s string, n int) string {
+	for ; n > 0 && s != ""; n-- {
+		_, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+	}
+	return s
+}
+
+// firstChars keeps only the first n characters of s.
+func firstChars(s string, n int) string {
+	end := 0
+	for ; n > 0 && end < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[end:])
+		end += size
+	}
+	return s[:end]
+}
+
+// foldRune maps r to the smallest rune it is equivalent to under
+// Unicode simple case folding, so that e.g. 'K', 'k' and the Kelvin
+// sign all compare equal.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// lineKey returns the part of line that is compared to find duplicates
+// and whether the line matched the -re pattern, if any. The line itself
+// is still what gets printed.
+func lineKey(line string, opts options) (string, bool) {
+	if !opts.keyed() {
+		return line, true
+	}
+
+	key := strings.TrimSuffix(line, string(opts.delimiter()))
+	if opts.ignoreCR {
+		key = strings.TrimSuffix(key, "\r")
+	}
+	if opts.pattern != nil {
+		match := opts.pattern.FindStringSubmatchIndex(key)
+		if match == nil || match[2*opts.group] < 0 {
+			return "", false
+		}
+		key = key[match[2*opts.group]:match[2*opts.group+1]]
+	}
+	if opts.trimTrailing {
+		key = strings.TrimRightFunc(key, unicode.IsSpace)
+	}
+	key = skipFields(key, opts.skipFields)
+	key = skipChars(key, opts.skipChars)
+	if opts.checkChars > 0 {
+		key = firstChars(key, opts.checkChars)
+	}
+	if opts.foldCase {
+		key = strings.Map(foldRune, key)
+	}
+	return key, true
+}
@@ -0,0 +1,127 @@
+package uniq
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeNums writes the line numbers nums separated by commas. With
+// -ranges consecutive numbers are written as first-last, and with
+// -maxnums N only the first N numbers or ranges are written, followed
+// by +M for the M numbers left out.
+func writeNums(buf *bytes.Buffer, nums []int, opts options) {
+	shown := 0
+	for i := 0; i < len(nums); {
+		if opts.maxNums > 0 && shown == opts.maxNums {
+			buf.WriteString(",+")
+			buf.WriteString(strconv.Itoa(len(nums) - i))
+			return
+		}
+
+		last := i
+		if opts.ranges {
+			for last+1 < len(nums) && nums[last+1] == nums[last]+1 {
+				last++
+			}
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Itoa(nums[i]))
+		if last > i {
+			buf.WriteByte('-')
+			buf.WriteString(strconv.Itoa(nums[last]))
+		}
+		shown++
+		i = last + 1
+	}
+}
+
+// cappedNums returns nums cut to -maxnums entries for JSON output.
+func cappedNums(nums []int, opts options) []int {
+	if opts.maxNums > 0 && len(nums) > opts.maxNums {
+		return nums[:opts.maxNums]
+	}
+	return nums
+}
+
+// recordText returns text without its delimiter, nor the CR before it
+// with -crlf.
+func recordText(text string, opts options) string {
+	text = strings.TrimSuffix(text, string(opts.delimiter()))
+	if opts.ignoreCR {
+		text = strings.TrimSuffix(text, "\r")
+	}
+	return text
+}
+
+// lineJSON is the JSON form of a printed line. lines is cut to -maxnums
+// numbers, count is the number of occurrences all the same.
+type lineJSON struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+	Lines []int  `json:"lines"`
+}
+
+// setLineJSON is the JSON form of a line of a set operation, with its
+// line numbers in each input.
+type setLineJSON struct {
+	Text  string  `json:"text"`
+	Count int     `json:"count"`
+	Lines [][]int `json:"lines"`
+}
+
+// jsonWriter writes values one per line, as NDJSON or as the elements
+// of a single JSON array. end must be called after the last value.
+type jsonWriter struct {
+	output io.Writer
+	array  bool
+	count  int
+	buf    bytes.Buffer
+}
+
+// newJSONWriter returns a jsonWriter if -json or -ndjson is set, nil
+// otherwise.
+func newJSONWriter(output io.Writer, opts options) *jsonWriter {
+	if !opts.json && !opts.ndjson {
+		return nil
+	}
+	return &jsonWriter{output: output, array: opts.json}
+}
+
+func (w *jsonWriter) write(v interface{}) error {
+	w.buf.Reset()
+	if w.array {
+		if w.count == 0 {
+			w.buf.WriteString("[\n")
+		} else {
+			w.buf.WriteByte(',')
+		}
+	}
+	w.count++
+
+	enc := json.NewEncoder(&w.buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := w.output.Write(w.buf.Bytes())
+	return err
+}
+
+func (w *jsonWriter) end() error {
+	if !w.array {
+		return nil
+	}
+
+	end := "]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.output, end)
+	return err
+}
@@ -0,0 +1,141 @@
+package uniq
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteNums(t *testing.T) {
+	tests := []struct {
+		nums     []int
+		opts     options
+		expected string
+	}{
+		{[]int{3, 4, 5, 6, 7, 9, 20}, options{}, "3,4,5,6,7,9,20"},
+		{[]int{3, 4, 5, 6, 7, 9, 20, 21}, options{ranges: true}, "3-7,9,20-21"},
+		{[]int{1, 2, 3, 4, 5}, options{maxNums: 2}, "1,2,+3"},
+		{[]int{1, 2, 3, 5, 7, 8}, options{ranges: true, maxNums: 2}, "1-3,5,+2"},
+		{[]int{1, 2}, options{maxNums: 2}, "1,2"},
+		{[]int{42}, options{ranges: true}, "42"},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		writeNums(&buf, test.nums, test.opts)
+		if buf.String() != test.expected {
+			t.Errorf("test index %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	input := "a\nb\r\na\n<a & b>\nb\r\na\n"
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"uniq", "-every", "-ndjson"},
+			`{"text":"a","count":3,"lines":[1,3,6]}
+{"text":"b\r","count":2,"lines":[2,5]}
+{"text":"<a & b>","count":1,"lines":[4]}
+`,
+		},
+		{
+			[]string{"uniq", "-dup", "-json", "-crlf", "-maxnums", "2"},
+			`[
+{"text":"a","count":3,"lines":[1,3]}
+,{"text":"b","count":2,"lines":[2,5]}
+]
+`,
+		},
+		{
+			[]string{"uniq", "-dup", "-json", "-re", "z", "-nomatch", "skip"},
+			"[]\n",
+		},
+		{
+			[]string{"uniq", "-adj", "-every", "-ndjson"},
+			`{"text":"a","count":1,"lines":[1]}
+{"text":"b\r","count":1,"lines":[2]}
+{"text":"a","count":1,"lines":[3]}
+{"text":"<a & b>","count":1,"lines":[4]}
+{"text":"b\r","count":1,"lines":[5]}
+{"text":"a","count":1,"lines":[6]}
+`,
+		},
+	}
+
+	for i, test := range tests {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+
+		var buf bytes.Buffer
+		if opts.adjacentOnly {
+			err = scanAdjacent(strings.NewReader(input), &buf, opts)
+		} else {
+			var lines []*Line
+			lines, err = scanLines(strings.NewReader(input), opts)
+			if err == nil {
+				err = uniq(&buf, lines, opts)
+			}
+		}
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("test index %d\nexpected:\n%s\noutput:\n%s", i, test.expected, buf.String())
+		}
+
+		if opts.json {
+			var v []lineJSON
+			if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+				t.Errorf("test index %d: invalid JSON: %v", i, err)
+			}
+		}
+	}
+}
+
+func TestAdjacentRanges(t *testing.T) {
+	opts, err := parseArgs([]string{"uniq", "-adj", "-every", "-num", "-ranges"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = scanAdjacent(strings.NewReader("a\na\na\nb\na\na\n"), &buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1-3: a\n4: b\n5-6: a\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestSetJSON(t *testing.T) {
+	sets := []*setLine{{text: "x\n", nums: [][]int{{1, 2}, nil}}}
+
+	var buf bytes.Buffer
+	if err := printSets(&buf, sets, options{ndjson: true}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"text":"x","count":2,"lines":[[1,2],[]]}` + "\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestParseJSONArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"uniq", "-json", "-ndjson"},
+		{"uniq", "-json", "-distinct"},
+		{"uniq", "-maxnums", "x"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
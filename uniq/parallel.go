@@ -0,0 +1,249 @@
+package uniq
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// chunkSize is the rough amount of input each worker deduplicates at a
+// time, chunks end right after a delimiter.
+var chunkSize int64 = 4 << 20
+
+// chunk is a line aligned byte range of an input file.
+type chunk struct {
+	file       *os.File
+	start, end int64
+	// last chunks may end with an unterminated record.
+	last bool
+}
+
+// chunkLine is a distinct line of a chunk, numbered from 1 at the start
+// of the chunk.
+type chunkLine struct {
+	hash        keyHash
+	text        string
+	nums        []int
+	matched     bool
+	passthrough bool
+}
+
+// chunkLines are the distinct lines of a chunk in order of first
+// appearance, and the number of records it holds.
+type chunkLines struct {
+	lines   []*chunkLine
+	records int
+	err     error
+}
+
+// seekable reports whether every input is a regular file, which parallel
+// scanning needs to split them.
+func seekable(names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		if name == "-" {
+			return false
+		}
+		info, err := os.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			return false
+		}
+	}
+	return true
+}
+
+// splitChunks cuts file in chunks of about chunkSize bytes, each ending
+// right after delim or at the end of the file.
+func splitChunks(file *os.File, delim byte) ([]chunk, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	var chunks []chunk
+	buf := make([]byte, 4096)
+	for start := int64(0); start < size; {
+		end := start + chunkSize
+		for end < size {
+			n, err := file.ReadAt(buf, end)
+			if i := bytes.IndexByte(buf[:n], delim); i >= 0 {
+				end += int64(i) + 1
+				break
+			}
+			end += int64(n)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if end > size {
+			end = size
+		}
+		chunks = append(chunks, chunk{file, start, end, end == size})
+		start = end
+	}
+	return chunks, nil
+}
+
+// scanChunk deduplicates the records of c by key hash.
+func scanChunk(c chunk, opts options) *chunkLines {
+	data := make([]byte, c.end-c.start)
+	if _, err := c.file.ReadAt(data, c.start); err != nil && err != io.EOF {
+		return &chunkLines{err: err}
+	}
+
+	delim := opts.delimiter()
+	if c.last && len(data) > 0 && data[len(data)-1] != delim {
+		data = append(data, delim)
+	}
+
+	result := &chunkLines{}
+	seen := make(map[keyHash]*chunkLine)
+	var noMatchLine *chunkLine
+
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, delim) + 1
+		if i == 0 {
+			i = len(data)
+		}
+		record := string(data[:i])
+		data = data[i:]
+		result.records++
+
+		key, matched := lineKey(record, opts)
+		var cl *chunkLine
+		if matched {
+			h := hashKey(key)
+			if cl = seen[h]; cl == nil {
+				cl = &chunkLine{hash: h, text: record, matched: true}
+				seen[h] = cl
+				result.lines = append(result.lines, cl)
+			}
+		} else {
+			switch opts.noMatch {
+			case noMatchSkip:
+				continue
+			case noMatchPass:
+				cl = &chunkLine{text: record, passthrough: true}
+				result.lines = append(result.lines, cl)
+			default:
+				if cl = noMatchLine; cl == nil {
+					cl = &chunkLine{text: record}
+					noMatchLine = cl
+					result.lines = append(result.lines, cl)
+				}
+			}
+		}
+		cl.nums = append(cl.nums, result.records)
+	}
+
+	return result
+}
+
+// mergeChunk adds the lines of the chunk following the ones already
+// scanned, as if its records had been read one by one.
+func (s *lineSet) mergeChunk(c *chunkLines) {
+	for _, cl := range c.lines {
+		for i := range cl.nums {
+			cl.nums[i] += s.lineNum
+		}
+		if cl.passthrough {
+			s.lines = append(s.lines, &Line{text: &cl.text, nums: cl.nums, passthrough: true})
+			continue
+		}
+
+		var linep *Line
+		if cl.matched {
+			linep = s.byHash[cl.hash]
+		} else {
+			linep = s.noMatchLine
+		}
+		if linep != nil {
+			linep.nums = append(linep.nums, cl.nums...)
+			continue
+		}
+
+		linep = &Line{text: &cl.text, nums: cl.nums[:1]}
+		if cl.matched {
+			s.byHash[cl.hash] = linep
+		} else {
+			s.noMatchLine = linep
+		}
+		var add bool
+		add, s.emptyAdded = shouldAddLine(linep, s.opts, s.emptyAdded)
+		if add {
+			s.lines = append(s.lines, linep)
+		}
+		linep.nums = cl.nums
+	}
+	s.lineNum += c.records
+}
+
+// scanParallel reads the named files splitting them in chunks that are
+// deduplicated by opts.jobs workers, merged back in input order.
+func (s *lineSet) scanParallel(names []string) error {
+	var chunks []chunk
+	for _, name := range names {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		fileChunks, err := splitChunks(file, s.opts.delimiter())
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, fileChunks...)
+	}
+
+	if s.byHash == nil {
+		s.byHash = make(map[keyHash]*Line)
+		for key, linep := range s.byKey {
+			s.byHash[hashKey(key)] = linep
+		}
+		s.byKey = nil
+	}
+
+	// Workers stay at most 2*jobs chunks ahead of the merge, so pending
+	// results don't pile up in memory.
+	results := make([]chan *chunkLines, len(chunks))
+	for i := range results {
+		results[i] = make(chan *chunkLines, 1)
+	}
+	slots := make(chan struct{}, 2*s.opts.jobs)
+	workers := make(chan struct{}, s.opts.jobs)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i, c := range chunks {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, c chunk) {
+				workers <- struct{}{}
+				result := scanChunk(c, s.opts)
+				<-workers
+				results[i] <- result
+			}(i, c)
+		}
+	}()
+
+	for _, result := range results {
+		c := <-result
+		<-slots
+		if c.err != nil {
+			return c.err
+		}
+		s.mergeChunk(c)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package uniq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeInputs writes contents to files in dir and returns their names.
+func writeInputs(t testing.TB, dir string, contents ...string) []string {
+	var names []string
+	for i, content := range contents {
+		name := filepath.Join(dir, fmt.Sprintf("in%d", i))
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// uniqOutput runs uniq over names, in parallel when opts.jobs > 1.
+func uniqOutput(t testing.TB, names []string, opts options) string {
+	set := newLineSet(opts)
+	defer set.Close()
+
+	if opts.jobs > 1 {
+		if err := set.scanParallel(names); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		input, closeInputs, err := openInputs(names, opts.delimiter(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeInputs()
+		if err := set.scan(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := uniq(&buf, rankLines(set.lines, opts), opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestScanParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uniq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var big bytes.Buffer
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&big, "line %d\n", i*7%113)
+		if i%17 == 0 {
+			big.WriteString("\n\nno digits here\n")
+		}
+	}
+	names := writeInputs(t, dir, big.String(), "", "line 5\nfresh\nline 5", "last")
+
+	defer func(size int64) { chunkSize = size }(chunkSize)
+	chunkSize = 100
+
+	for i, args := range [][]string{
+		{"uniq", "-merge"},
+		{"uniq", "-merge", "-every", "-num", "-empty"},
+		{"uniq", "-merge", "-dup", "-count"},
+		{"uniq", "-merge", "-every", "-freq", "-num"},
+		{"uniq", "-merge", "-every", "-top", "3", "-count"},
+		{"uniq", "-merge", "-every", "-num", "-re", `\d+`},
+		{"uniq", "-merge", "-every", "-num", "-re", `\d+`, "-nomatch", "count"},
+		{"uniq", "-merge", "-every", "-num", "-re", `\d+`, "-nomatch", "skip"},
+	} {
+		opts, err := parseArgs(args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		expected := uniqOutput(t, names, opts)
+
+		opts.jobs = 4
+		if output := uniqOutput(t, names, opts); output != expected {
+			t.Errorf("test index %d: parallel output diverges\nexpected:\n%q\noutput:\n%q",
+				i, expected, output)
+		}
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uniq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	names := writeInputs(t, dir, "aaaa\nbb\ncccccc\nd\ne")
+	file, err := os.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	defer func(size int64) { chunkSize = size }(chunkSize)
+	chunkSize = 3
+
+	chunks, err := splitChunks(file, '\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range chunks {
+		got = append(got, fmt.Sprintf("%d-%d %v", c.start, c.end, c.last))
+	}
+	expected := "[0-5 false 5-15 false 15-18 true]"
+	if fmt.Sprint(got) != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+var benchInput string
+
+func TestMain(m *testing.M) {
+	status := m.Run()
+	if benchInput != "" {
+		os.Remove(benchInput)
+	}
+	os.Exit(status)
+}
+
+// benchmarkInput returns a generated input file of about 256MB, with a
+// few hundred thousand distinct lines.
+func benchmarkInput(b *testing.B) string {
+	if benchInput != "" {
+		return benchInput
+	}
+
+	file, err := ioutil.TempFile("", "uniq-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for size, i := 0, 0; size < 256<<20; i++ {
+		n, _ := fmt.Fprintf(w, "2017-01-02 10:%02d:%02d request id=%d status=%d\n",
+			i%60, i%59, i*7919%300000, 200+i%5)
+		size += n
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+
+	benchInput = file.Name()
+	return benchInput
+}
+
+func benchmarkScan(b *testing.B, jobs int) {
+	name := benchmarkInput(b)
+	info, err := os.Stat(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(info.Size())
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		opts := options{printEveryOnce: true, printLineNumber: true, jobs: jobs}
+		uniqOutput(b, []string{name}, opts)
+	}
+}
+
+func BenchmarkScanSequential(b *testing.B) {
+	benchmarkScan(b, 1)
+}
+
+func BenchmarkScanParallel4(b *testing.B) {
+	benchmarkScan(b, 4)
+}
+
+func BenchmarkScanParallel8(b *testing.B) {
+	benchmarkScan(b, 8)
+}
@@ -0,0 +1,78 @@
+package uniq
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// lineRank orders lines by their number of occurrences, descending
+// unless ascending is set. Ties keep the order of first appearance.
+type lineRank struct {
+	ascending bool
+}
+
+func (r lineRank) before(a, b *Line) bool {
+	if ca, cb := len(a.nums), len(b.nums); ca != cb {
+		if r.ascending {
+			return ca < cb
+		}
+		return ca > cb
+	}
+	return a.nums[0] < b.nums[0]
+}
+
+// topHeap keeps the best ranked lines seen so far, with the worst one
+// at the root so it is the one evicted.
+type topHeap struct {
+	rank  lineRank
+	lines []*Line
+}
+
+func (h *topHeap) Len() int           { return len(h.lines) }
+func (h *topHeap) Less(i, j int) bool { return h.rank.before(h.lines[j], h.lines[i]) }
+func (h *topHeap) Swap(i, j int)      { h.lines[i], h.lines[j] = h.lines[j], h.lines[i] }
+func (h *topHeap) Push(x interface{}) { h.lines = append(h.lines, x.(*Line)) }
+
+func (h *topHeap) Pop() interface{} {
+	last := h.lines[len(h.lines)-1]
+	h.lines = h.lines[:len(h.lines)-1]
+	return last
+}
+
+// rankLines returns the lines that would be printed, ordered by count.
+// With opts.top set only the first top lines are kept in a heap, which
+// saves sorting the others; linesp still holds every distinct line.
+func rankLines(linesp []*Line, opts options) []*Line {
+	if !opts.sortByCount && opts.top == 0 {
+		return linesp
+	}
+	rank := lineRank{ascending: opts.ascending}
+
+	var ranked []*Line
+	if opts.top > 0 {
+		h := &topHeap{rank: rank}
+		for _, linep := range linesp {
+			if !shouldPrint(linep, opts) {
+				continue
+			}
+			if h.Len() < opts.top {
+				heap.Push(h, linep)
+			} else if rank.before(linep, h.lines[0]) {
+				h.lines[0] = linep
+				heap.Fix(h, 0)
+			}
+		}
+		ranked = h.lines
+	} else {
+		for _, linep := range linesp {
+			if shouldPrint(linep, opts) {
+				ranked = append(ranked, linep)
+			}
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return rank.before(ranked[i], ranked[j])
+	})
+	return ranked
+}
@@ -0,0 +1,130 @@
+package uniq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Set operations across inputs.
+const (
+	setIntersect = "intersect"
+	setDiff      = "diff"
+	setSymDiff   = "symdiff"
+)
+
+// setLine is a distinct line and its numbers in each input, nil for the
+// inputs lacking it.
+type setLine struct {
+	text string
+	nums [][]int
+}
+
+// in returns the number of inputs holding the line.
+func (l *setLine) in() int {
+	n := 0
+	for _, nums := range l.nums {
+		if len(nums) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// compareSets returns the lines selected by op, in order of appearance
+// in the inputs. Unlike comm the inputs needn't be sorted. Records not
+// matching -re take no part.
+func compareSets(sets []*lineSet, op string) ([]*setLine, error) {
+	byKey := make(map[string]*setLine)
+	var ordered []*setLine
+
+	for i, set := range sets {
+		for _, linep := range set.lines {
+			if linep.passthrough {
+				continue
+			}
+			text, err := linep.content()
+			if err != nil {
+				return nil, err
+			}
+			key, matched := lineKey(text, set.opts)
+			if !matched {
+				continue
+			}
+
+			sl := byKey[key]
+			if sl == nil {
+				sl = &setLine{text: text, nums: make([][]int, len(sets))}
+				byKey[key] = sl
+				ordered = append(ordered, sl)
+			}
+			sl.nums[i] = linep.nums
+		}
+	}
+
+	var selected []*setLine
+	for _, sl := range ordered {
+		var keep bool
+		switch in := sl.in(); op {
+		case setIntersect:
+			keep = in == len(sets)
+		case setDiff:
+			keep = in == 1 && len(sl.nums[0]) > 0
+		case setSymDiff:
+			keep = in == 1
+		}
+		if keep {
+			selected = append(selected, sl)
+		}
+	}
+	return selected, nil
+}
+
+// printSets prints the lines with -count the total of their occurrences
+// and with -num their line numbers in each input, separated by ';'.
+func printSets(output io.Writer, lines []*setLine, opts options) error {
+	var buf bytes.Buffer
+	jw := newJSONWriter(output, opts)
+
+	for _, sl := range lines {
+		total := 0
+		for _, nums := range sl.nums {
+			total += len(nums)
+		}
+
+		if jw != nil {
+			nums := make([][]int, len(sl.nums))
+			for i := range sl.nums {
+				nums[i] = append([]int{}, cappedNums(sl.nums[i], opts)...)
+			}
+			if err := jw.write(setLineJSON{recordText(sl.text, opts), total, nums}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		buf.Reset()
+		if opts.printCount {
+			fmt.Fprintf(&buf, "%7d ", total)
+		}
+		if opts.printLineNumber {
+			for i, nums := range sl.nums {
+				if i > 0 {
+					buf.WriteByte(';')
+				}
+				writeNums(&buf, nums, opts)
+			}
+			buf.WriteString(": ")
+		}
+		buf.WriteString(sl.text)
+
+		if _, err := output.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if jw != nil {
+		return jw.end()
+	}
+	return nil
+}
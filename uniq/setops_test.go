@@ -0,0 +1,88 @@
+package uniq
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetOperations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uniq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	names := writeInputs(t, dir,
+		"PATH=/bin\nHOME=/root\nLANG=C\nHOME=/root\nTERM=xterm\n",
+		"LANG=C\nPATH=/usr/bin\nHOME=/root\n",
+		"HOME=/root\nLANG=C\nTZ=UTC\nLANG=C")
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"uniq", "-intersect", "-num"},
+			"2,4;3;1: HOME=/root\n3;1;2,4: LANG=C\n",
+		},
+		{
+			[]string{"uniq", "-diff", "-num"},
+			"1;;: PATH=/bin\n5;;: TERM=xterm\n",
+		},
+		{
+			[]string{"uniq", "-symdiff", "-count"},
+			"      1 PATH=/bin\n      1 TERM=xterm\n      1 PATH=/usr/bin\n      1 TZ=UTC\n",
+		},
+		{
+			[]string{"uniq", "-intersect", "-re", `^[A-Z]+`, "-num"},
+			"1;2: PATH=/bin\n2,4;3: HOME=/root\n3;1: LANG=C\n",
+		},
+	}
+
+	for i, test := range tests {
+		args := append(test.args, names[0], names[1])
+		if i != 3 {
+			args = append(args, names[2])
+		}
+		opts, err := parseArgs(args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		opts.output = out
+
+		if err := process(opts, nil, nil); err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		output, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("test index %d\nexpected:\n%q\noutput:\n%q", i, test.expected, output)
+		}
+	}
+}
+
+func TestParseSetArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"uniq", "-intersect", "a"},
+		{"uniq", "-diff", "-adj", "a", "b"},
+		{"uniq", "-symdiff", "-freq", "a", "b"},
+		{"uniq", "-intersect", "-distinct", "a", "b"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+
+	opts, err := parseArgs([]string{"uniq", "-symdiff", "a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.inputs) != 3 || opts.output != "" {
+		t.Errorf("unexpected operands %v %q", opts.inputs, opts.output)
+	}
+}
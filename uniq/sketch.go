@@ -0,0 +1,225 @@
+package uniq
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	defaultPrecision = 14
+	defaultCounters  = 1000
+)
+
+// hash64 returns a 64 bits hash of key. FNV alone mixes the high bits
+// poorly, the murmur3 finalizer spreads it as HyperLogLog needs.
+func hash64(key string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, key)
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hyperLogLog estimates the number of distinct keys added to it with
+// 2^precision registers, a standard error of about 1.04/√(2^precision).
+type hyperLogLog struct {
+	precision uint
+	registers []uint8
+}
+
+func newHyperLogLog(precision uint) *hyperLogLog {
+	return &hyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+func (h *hyperLogLog) add(key string) {
+	x := hash64(key)
+	index := x >> (64 - h.precision)
+	// The guard bit bounds the rank when the remaining bits are zero.
+	rank := uint8(bits.LeadingZeros64(x<<h.precision|1<<(h.precision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+func (h *hyperLogLog) count() uint64 {
+	m := float64(len(h.registers))
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// counter is a Space-Saving counter. count overestimates the occurrences
+// of key by at most err.
+type counter struct {
+	key   string
+	text  string
+	count int
+	err   int
+	index int
+}
+
+// counterHeap orders counters by count, the smallest at the root.
+type counterHeap []*counter
+
+func (h counterHeap) Len() int           { return len(h) }
+func (h counterHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h counterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *counterHeap) Push(x interface{}) {
+	c := x.(*counter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *counterHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// spaceSaving tracks the most frequent keys with a fixed number of
+// counters. Any key occurring more than n/len(counters) times out of n
+// is kept, and no count is off by more than that.
+type spaceSaving struct {
+	size     int
+	total    int
+	counters map[string]*counter
+	heap     counterHeap
+}
+
+func newSpaceSaving(size int) *spaceSaving {
+	return &spaceSaving{size: size, counters: make(map[string]*counter, size)}
+}
+
+func (s *spaceSaving) add(key, text string) {
+	s.total++
+	if c := s.counters[key]; c != nil {
+		c.count++
+		heap.Fix(&s.heap, c.index)
+		return
+	}
+
+	if len(s.heap) < s.size {
+		c := &counter{key: key, text: text, count: 1}
+		s.counters[key] = c
+		heap.Push(&s.heap, c)
+		return
+	}
+
+	// The least counted key makes room, its count becomes the error.
+	c := s.heap[0]
+	delete(s.counters, c.key)
+	c.key, c.text, c.err = key, text, c.count
+	c.count++
+	s.counters[key] = c
+	heap.Fix(&s.heap, 0)
+}
+
+// heavy returns the counters above percent of all the keys added, the
+// most frequent first.
+func (s *spaceSaving) heavy(percent float64) []*counter {
+	var found []*counter
+	for _, c := range s.heap {
+		if float64(c.count)*100 > percent*float64(s.total) {
+			found = append(found, c)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].count != found[j].count {
+			return found[i].count > found[j].count
+		}
+		return found[i].text < found[j].text
+	})
+	return found
+}
+
+// estimate reads input once with memory bounded by the sketch sizes and
+// prints either the approximate number of distinct keys or the keys
+// making up more than opts.heavy percent of the records. Records not
+// matching -re aren't counted.
+func estimate(input io.Reader, output io.Writer, opts options) error {
+	reader := bufio.NewReader(input)
+
+	var hll *hyperLogLog
+	var ss *spaceSaving
+	if opts.distinct {
+		hll = newHyperLogLog(opts.precision)
+	} else {
+		ss = newSpaceSaving(opts.counters)
+	}
+
+	for {
+		record, err := readRecord(reader, opts)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key, matched := lineKey(record, opts)
+		if !matched {
+			continue
+		}
+		if hll != nil {
+			hll.add(key)
+		} else {
+			ss.add(key, record)
+		}
+	}
+
+	if hll != nil {
+		_, err := fmt.Fprintln(output, hll.count())
+		return err
+	}
+	for _, c := range ss.heavy(opts.heavy) {
+		if _, err := fmt.Fprintf(output, "%7d %s", c.count, c.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package uniq
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHyperLogLogErrorBound(t *testing.T) {
+	for _, precision := range []uint{4, 10, 14} {
+		for _, distinct := range []int{10, 1000, 50000, 200000} {
+			h := newHyperLogLog(precision)
+			for i := 0; i < distinct; i++ {
+				key := fmt.Sprintf("10.%d.%d.%d", i>>16, i>>8&0xff, i&0xff)
+				// Repeats must not change the estimate.
+				h.add(key)
+				h.add(key)
+			}
+
+			// Three standard errors, plus one for tiny counts.
+			stdErr := 1.04 / math.Sqrt(float64(uint(1)<<precision))
+			bound := 3*stdErr*float64(distinct) + 1
+
+			got := float64(h.count())
+			if math.Abs(got-float64(distinct)) > bound {
+				t.Errorf("precision %d: estimated %v distinct keys of %d, error bound %.1f",
+					precision, got, distinct, bound)
+			}
+		}
+	}
+}
+
+func TestSpaceSavingErrorBound(t *testing.T) {
+	const records, size = 100000, 100
+
+	rnd := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rnd, 1.2, 1, 5000)
+
+	exact := make(map[string]int)
+	ss := newSpaceSaving(size)
+	for i := 0; i < records; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		exact[key]++
+		ss.add(key, key+"\n")
+	}
+
+	// Every key above records/size occurrences must be kept, and no
+	// count may be off by more than that.
+	maxErr := records / size
+	for key, n := range exact {
+		c := ss.counters[key]
+		if c == nil {
+			if n > maxErr {
+				t.Errorf("%s: %d occurrences but dropped", key, n)
+			}
+			continue
+		}
+		if c.count < n || c.count-c.err > n || c.count-n > maxErr {
+			t.Errorf("%s: %d occurrences, counted %d±%d", key, n, c.count, c.err)
+		}
+	}
+
+	// Heavy hitters are reported by count, the true ones included.
+	heavy := ss.heavy(1)
+	for i, c := range heavy {
+		if c.count*100 <= records || (i > 0 && c.count > heavy[i-1].count) {
+			t.Errorf("%s: counted %d, misplaced heavy hitter", c.key, c.count)
+		}
+	}
+	for key, n := range exact {
+		if n*100 <= records {
+			continue
+		}
+		if c := ss.counters[key]; c == nil || c.count*100 <= records {
+			t.Errorf("%s: %d occurrences, missing from the heavy hitters", key, n)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "GET /%d\n", i%40)
+		if i%4 == 0 {
+			input.WriteString("GET /popular\n")
+		}
+		if i%10 == 0 {
+			input.WriteString("HEAD /\n")
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"uniq", "-distinct"}, "42\n"},
+		{[]string{"uniq", "-distinct", "-re", `/\d+`}, "40\n"},
+		{[]string{"uniq", "-heavy", "5"}, "    250 GET /popular\n    100 HEAD /\n"},
+		{[]string{"uniq", "-heavy", "10", "-i"}, "    250 GET /popular\n"},
+	}
+
+	for i, test := range tests {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+
+		var buf bytes.Buffer
+		if err := estimate(strings.NewReader(input.String()), &buf, opts); err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("test index %d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+}
+
+func TestParseEstimateArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"uniq", "-distinct", "-heavy", "1"},
+		{"uniq", "-adj", "-distinct"},
+		{"uniq", "-distinct", "-precision", "3"},
+		{"uniq", "-distinct", "-precision", "19"},
+		{"uniq", "-heavy", "0"},
+		{"uniq", "-heavy", "101"},
+		{"uniq", "-heavy", "1", "-counters", "0"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package uniq
+
+import (
+	"bufio"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+)
+
+// hashSize is the size of the digests keying lines once spilled. Texts
+// no longer than that stay in memory, spilling them would save nothing.
+const hashSize = 16
+
+type keyHash [hashSize]byte
+
+// hashKey returns the 128 bits FNV-1a digest of key. Two distinct keys
+// sharing a digest would be taken as duplicates, a chance of about
+// n²/2¹²⁹ for n distinct keys.
+func hashKey(key string) keyHash {
+	var sum keyHash
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	h.Sum(sum[:0])
+	return sum
+}
+
+// spillFile is a temporary file holding the text of lines moved out of
+// memory, read back by offset when they are printed.
+type spillFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+}
+
+func newSpillFile() (*spillFile, error) {
+	file, err := ioutil.TempFile("", "uniq")
+	if err != nil {
+		return nil, err
+	}
+	return &spillFile{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// store appends text to the file and returns its offset.
+func (f *spillFile) store(text string) (int64, error) {
+	offset := f.size
+	n, err := f.writer.WriteString(text)
+	f.size += int64(n)
+	return offset, err
+}
+
+// load reads back size bytes stored at offset.
+func (f *spillFile) load(offset int64, size int) (string, error) {
+	if f.writer.Buffered() > 0 {
+		if err := f.writer.Flush(); err != nil {
+			return "", err
+		}
+	}
+	buf := make([]byte, size)
+	if _, err := f.file.ReadAt(buf, offset); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// Close closes and removes the file.
+func (f *spillFile) Close() error {
+	err := f.file.Close()
+	if rmErr := os.Remove(f.file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,763 @@
+package uniq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/c0defellas/enzo/internal/cli"
+)
+
+type options struct {
+	adjacentOnly    bool
+	printDuplicates bool
+	printEmptyLines bool
+	printEveryOnce  bool
+	printLineNumber bool
+	printCount      bool
+	sortByCount     bool
+	ascending       bool
+	top             int
+	skipFields      int
+	skipChars       int
+	checkChars      int
+	foldCase        bool
+	trimTrailing    bool
+	pattern         *regexp.Regexp
+	group           int
+	noMatch         string
+	zeroDelim       bool
+	delim           byte
+	ignoreCR        bool
+	merge           bool
+	memLimit        int64
+	jobs            int
+	distinct        bool
+	precision       uint
+	heavy           float64
+	counters        int
+	setOp           string
+	ranges          bool
+	maxNums         int
+	json            bool
+	ndjson          bool
+	inputs          []string
+	output          string
+}
+
+type Line struct {
+	text *string
+	nums []int
+	// passthrough lines didn't match -re and are printed as they are.
+	passthrough bool
+	// spill holds the text at offset once it is moved out of memory,
+	// text is nil then.
+	spill  *spillFile
+	offset int64
+	size   int
+}
+
+// content returns the text of the line, read back from the spill file
+// if needed.
+func (l *Line) content() (string, error) {
+	if l.text != nil {
+		return *l.text, nil
+	}
+	return l.spill.load(l.offset, l.size)
+}
+
+// empty reports whether the line is an empty record. Spilled lines are
+// never empty.
+func (l *Line) empty(opts options) bool {
+	return l.text != nil && isEmpty(*l.text, opts)
+}
+
+// run is a group of identical adjacent lines.
+type run struct {
+	key         string
+	matched     bool
+	passthrough bool
+	text        string
+	first       int
+	count       int
+}
+
+// count returns a setter parsing a non-negative number into *p.
+func count(p *int) func(string) error {
+	return func(val string) error {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return errors.New("not a count")
+		}
+		*p = n
+		return nil
+	}
+}
+
+// parseSize parses a byte size with an optional K, M or G binary
+// suffix.
+func parseSize(val string) (int64, error) {
+	digits, unit := val, int64(1)
+	if n := len(val); n > 0 {
+		if shift := strings.IndexByte("KMG", val[n-1]); shift >= 0 {
+			digits, unit = val[:n-1], 1<<(10*uint(shift+1))
+		}
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("not a size")
+	}
+	return n * unit, nil
+}
+
+// parsePercent parses a percentage above 0 and up to 100.
+func parsePercent(val string) (float64, error) {
+	pct, err := strconv.ParseFloat(val, 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, errors.New("not a percentage")
+	}
+	return pct, nil
+}
+
+// parseByte parses a single byte, which may be written as a Go escape
+// such as \t or \x00.
+func parseByte(val string) (byte, error) {
+	if len(val) == 1 {
+		return val[0], nil
+	}
+
+	r, _, tail, err := strconv.UnquoteChar(val, '\'')
+	if err != nil || tail != "" || r > 0xff {
+		return 0, errors.New("not a single byte")
+	}
+	return byte(r), nil
+}
+
+func newFlagSet(name string, opts *options, expr, group *string) *cli.FlagSet {
+	flags := cli.NewFlagSet(name,
+		"[OPTION]... [INPUT [OUTPUT]]",
+		"--merge [OPTION]... INPUT...",
+		"--intersect|--diff|--symdiff [OPTION]... INPUT INPUT...")
+
+	flags.Bool(&opts.adjacentOnly, 0, "adj", "collapse only adjacent duplicates, streaming")
+	flags.Bool(&opts.printDuplicates, 'd', "dup", "print only duplicated lines")
+	flags.Bool(&opts.printEmptyLines, 0, "empty", "print empty lines once")
+	flags.Bool(&opts.printEveryOnce, 0, "every", "print every distinct line once")
+	flags.Bool(&opts.printLineNumber, 0, "num", "prefix lines with their line numbers")
+	flags.Bool(&opts.printCount, 'c', "count", "prefix lines with their number of occurrences")
+	flags.Bool(&opts.ranges, 0, "ranges", "write consecutive line numbers as first-last")
+	flags.Func(0, "maxnums", "N", "show at most N line numbers or ranges", count(&opts.maxNums))
+	flags.Bool(&opts.json, 0, "json", "print a JSON array of {text, count, lines}")
+	flags.Bool(&opts.ndjson, 0, "ndjson", "print one JSON {text, count, lines} per line")
+	flags.Bool(&opts.sortByCount, 0, "freq", "order lines by count, most frequent first; implies -every without -dup")
+	flags.BoolFunc(0, "freq-asc", "order lines by count, least frequent first", func() {
+		opts.sortByCount = true
+		opts.ascending = true
+	})
+	flags.Func(0, "top", "N", "print only the N first lines by count", count(&opts.top))
+	flags.Func('f', "skip-fields", "N", "compare lines without their N first fields", count(&opts.skipFields))
+	flags.Func('s', "skip-chars", "N", "compare lines without their N first characters", count(&opts.skipChars))
+	flags.Func('w', "check-chars", "N", "compare at most N characters of lines", count(&opts.checkChars))
+	flags.Bool(&opts.foldCase, 'i', "ignore-case", "ignore differences in case")
+	flags.Bool(&opts.trimTrailing, 0, "rtrim", "ignore trailing blanks")
+	flags.String(expr, 0, "re", "REGEX", "compare lines by what REGEX matches")
+	flags.String(group, 0, "group", "N|NAME", "compare by the capture group N or NAME of -re")
+	flags.String(&opts.noMatch, 0, "nomatch", "WHAT",
+		"pass, skip or count the lines -re doesn't match")
+	flags.Bool(&opts.zeroDelim, 'z', "zero-terminated", "end records with NUL, not newline")
+	flags.Func(0, "delim", "DELIM", "end records with the byte DELIM", func(val string) error {
+		delim, err := parseByte(val)
+		opts.delim, opts.zeroDelim = delim, delim == 0
+		return err
+	})
+	flags.Bool(&opts.ignoreCR, 0, "crlf", "ignore a CR before the delimiter")
+	flags.Bool(&opts.merge, 0, "merge", "read all the operands as inputs, deduplicated together")
+	flags.Func(0, "mem", "SIZE", "spill lines to a temporary file past SIZE bytes, e.g. 64M",
+		func(val string) (err error) {
+			opts.memLimit, err = parseSize(val)
+			return err
+		})
+	flags.Func('j', "jobs", "N", "deduplicate seekable inputs with N workers", count(&opts.jobs))
+	flags.Bool(&opts.distinct, 0, "distinct", "estimate the number of distinct lines")
+	flags.Func(0, "precision", "P", "use 2^P registers for -distinct, from 4 to 18",
+		func(val string) error {
+			var precision int
+			err := count(&precision)(val)
+			opts.precision = uint(precision)
+			return err
+		})
+	flags.Func(0, "heavy", "PCT", "estimate the lines making up more than PCT% of the input",
+		func(val string) (err error) {
+			opts.heavy, err = parsePercent(val)
+			return err
+		})
+	flags.Func(0, "counters", "N", "use N counters for -heavy", count(&opts.counters))
+	flags.BoolFunc(0, "intersect", "print the lines common to all inputs", func() { opts.setOp = setIntersect })
+	flags.BoolFunc(0, "diff", "print the lines only in the first input", func() { opts.setOp = setDiff })
+	flags.BoolFunc(0, "symdiff", "print the lines in exactly one input", func() { opts.setOp = setSymDiff })
+
+	// -dup, -empty, -every and -num shipped as flag package options, and
+	// the ones added since, before combined short options, were written
+	// after a single dash as well
+	flags.SingleDash("adj", "count", "counters", "crlf", "delim", "diff", "distinct", "dup",
+		"empty", "every", "freq", "freq-asc", "group", "heavy", "intersect", "json",
+		"maxnums", "mem", "merge", "ndjson", "nomatch", "num", "precision", "ranges",
+		"re", "rtrim", "symdiff", "top")
+	return flags
+}
+
+func parseArgs(args []string) (options, error) {
+	opts, _, err := parseCommandLine(args)
+	return opts, err
+}
+
+// parseCommandLine parses args, args[0] being the command name. The
+// returned flags report errors along with the usage.
+func parseCommandLine(args []string) (options, *cli.FlagSet, error) {
+	var opts options
+	var expr, group string
+	var err error
+
+	name := "uniq"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	opts.noMatch = noMatchPass
+	opts.precision = defaultPrecision
+	opts.counters = defaultCounters
+
+	flags := newFlagSet(name, &opts, &expr, &group)
+	if err := flags.Parse(args); err != nil {
+		return options{}, flags, err
+	}
+	operands := flags.Args()
+
+	if opts.printEveryOnce && opts.printDuplicates {
+		return options{}, flags, errors.New("choose -dup or -every")
+	}
+	if opts.adjacentOnly && (opts.sortByCount || opts.top > 0) {
+		return options{}, flags, errors.New("-freq, -freq-asc and -top can't stream, drop -adj")
+	}
+	if opts.distinct && opts.heavy > 0 {
+		return options{}, flags, errors.New("choose -distinct or -heavy")
+	}
+	if opts.adjacentOnly && (opts.distinct || opts.heavy > 0) {
+		return options{}, flags, errors.New("-distinct and -heavy already stream, drop -adj")
+	}
+	if opts.precision < 4 || opts.precision > 18 {
+		return options{}, flags, errors.New("-precision takes a value from 4 to 18")
+	}
+	if opts.counters < 1 {
+		return options{}, flags, errors.New("-counters needs at least one counter")
+	}
+	if opts.json && opts.ndjson {
+		return options{}, flags, errors.New("choose -json or -ndjson")
+	}
+	if (opts.json || opts.ndjson) && (opts.distinct || opts.heavy > 0) {
+		return options{}, flags, errors.New("-distinct and -heavy have no JSON output")
+	}
+	if opts.setOp != "" && (opts.adjacentOnly || opts.sortByCount || opts.top > 0 ||
+		opts.distinct || opts.heavy > 0) {
+		return options{}, flags, errors.New("-intersect, -diff and -symdiff can't be used with -adj, -freq, -top, -distinct or -heavy")
+	}
+	// lines seen once all tie, so ranking ranks every line unless
+	// asked for the duplicated ones
+	if (opts.sortByCount || opts.top > 0) && !opts.printDuplicates {
+		opts.printEveryOnce = true
+	}
+	switch opts.noMatch {
+	case noMatchPass, noMatchSkip, noMatchCount:
+	default:
+		return options{}, flags, errors.New("-nomatch takes pass, skip or count")
+	}
+	if expr != "" {
+		opts.pattern, opts.group, err = compilePattern(expr, group)
+		if err != nil {
+			return options{}, flags, err
+		}
+	}
+
+	switch {
+	case opts.setOp != "" && len(operands) < 2:
+		return options{}, flags, errors.New("-" + opts.setOp + " compares two inputs or more")
+	case opts.merge || opts.setOp != "":
+		opts.inputs = operands
+	case len(operands) > 2:
+		return options{}, flags, errors.New("too many operands, use -merge to read several inputs")
+	case len(operands) == 2:
+		opts.inputs, opts.output = operands[:1], operands[1]
+	default:
+		opts.inputs = operands
+	}
+
+	return opts, flags, nil
+}
+
+// delimiter returns the byte ending every record.
+func (opts options) delimiter() byte {
+	if opts.zeroDelim {
+		return 0
+	}
+	if opts.delim != 0 {
+		return opts.delim
+	}
+	return '\n'
+}
+
+// isEmpty reports whether text, a record with its delimiter, is empty.
+func isEmpty(text string, opts options) bool {
+	delim := string(opts.delimiter())
+	return text == delim || (opts.ignoreCR && text == "\r"+delim)
+}
+
+// readRecord reads the next record, delimiter included. An unterminated
+// last record gets the delimiter appended, so that it compares equal to
+// the same record elsewhere in the input and prints the same.
+func readRecord(reader *bufio.Reader, opts options) (string, error) {
+	delim := opts.delimiter()
+
+	record, err := reader.ReadBytes(delim)
+	if err == io.EOF && len(record) > 0 {
+		return string(append(record, delim)), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(record), nil
+}
+
+func shouldAddLine(linep *Line, opts options, emptyAdded bool) (bool, bool) {
+	if linep.empty(opts) {
+		if opts.printEmptyLines && !emptyAdded {
+			return true, true
+		}
+	} else if len(linep.nums) == 1 {
+		return true, emptyAdded
+	}
+
+	return false, emptyAdded
+}
+
+// lineSet collects the distinct lines of its inputs, in order of first
+// appearance. With opts.memLimit set, once the text and keys it holds
+// outgrow the limit the texts move to a spill file and the keys are
+// replaced by their hashes.
+type lineSet struct {
+	opts        options
+	lines       []*Line
+	byKey       map[string]*Line
+	byHash      map[keyHash]*Line
+	noMatchLine *Line
+	lineNum     int
+	emptyAdded  bool
+	used        int64
+	spill       *spillFile
+}
+
+func newLineSet(opts options) *lineSet {
+	return &lineSet{opts: opts, byKey: make(map[string]*Line)}
+}
+
+func (s *lineSet) lookup(key string) *Line {
+	if s.byHash != nil {
+		return s.byHash[hashKey(key)]
+	}
+	return s.byKey[key]
+}
+
+func (s *lineSet) insert(key string, linep *Line) {
+	if s.byHash != nil {
+		s.byHash[hashKey(key)] = linep
+		return
+	}
+	s.byKey[key] = linep
+	s.used += int64(len(key))
+}
+
+// keep sets the text of a new line, in memory or in the spill file.
+func (s *lineSet) keep(linep *Line, text string) error {
+	if s.spill == nil || len(text) <= hashSize {
+		linep.text = &text
+		s.used += int64(len(text))
+		return nil
+	}
+
+	offset, err := s.spill.store(text)
+	if err != nil {
+		return err
+	}
+	linep.spill, linep.offset, linep.size = s.spill, offset, len(text)
+	return nil
+}
+
+// spillOver moves the texts of the lines seen so far to a spill file and
+// rekeys them by hash, once the memory limit is crossed.
+func (s *lineSet) spillOver() error {
+	if s.spill != nil || s.opts.memLimit == 0 || s.used <= s.opts.memLimit {
+		return nil
+	}
+
+	spill, err := newSpillFile()
+	if err != nil {
+		return err
+	}
+	s.spill = spill
+
+	for _, linep := range s.lines {
+		if linep.text == nil || len(*linep.text) <= hashSize {
+			continue
+		}
+		text := *linep.text
+		linep.text = nil
+		if err := s.keep(linep, text); err != nil {
+			return err
+		}
+	}
+
+	s.byHash = make(map[keyHash]*Line, len(s.byKey))
+	for key, linep := range s.byKey {
+		s.byHash[hashKey(key)] = linep
+	}
+	s.byKey = nil
+	return nil
+}
+
+// scan reads the records of input. Line numbers carry on from previous
+// inputs.
+func (s *lineSet) scan(input io.Reader) error {
+	opts := s.opts
+	reader := bufio.NewReader(input)
+
+	add := false
+	for {
+		lineStr, err := readRecord(reader, opts)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.lineNum++
+
+		key, matched := lineKey(lineStr, opts)
+		linep := s.lookup(key)
+		if !matched {
+			switch opts.noMatch {
+			case noMatchSkip:
+				continue
+			case noMatchPass:
+				linep = &Line{nums: []int{s.lineNum}, passthrough: true}
+				if err := s.keep(linep, lineStr); err != nil {
+					return err
+				}
+				s.lines = append(s.lines, linep)
+				if err := s.spillOver(); err != nil {
+					return err
+				}
+				continue
+			}
+			linep = s.noMatchLine
+		}
+		if linep == nil {
+			linep = &Line{}
+			if err := s.keep(linep, lineStr); err != nil {
+				return err
+			}
+			if matched {
+				s.insert(key, linep)
+			} else {
+				s.noMatchLine = linep
+			}
+		}
+		linep.nums = append(linep.nums, s.lineNum)
+		add, s.emptyAdded = shouldAddLine(linep, opts, s.emptyAdded)
+		if add {
+			s.lines = append(s.lines, linep)
+		}
+		if err := s.spillOver(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close removes the spill file, if any.
+func (s *lineSet) Close() error {
+	if s.spill == nil {
+		return nil
+	}
+	return s.spill.Close()
+}
+
+// scanLines returns the distinct lines of input, keeping them all in
+// memory.
+func scanLines(input io.Reader, opts options) ([]*Line, error) {
+	opts.memLimit = 0
+	s := newLineSet(opts)
+	if err := s.scan(input); err != nil {
+		return nil, err
+	}
+	return s.lines, nil
+}
+
+func shouldPrint(linep *Line, opts options) bool {
+	lineCount := len(linep.nums)
+
+	if linep.empty(opts) || linep.passthrough {
+		return true
+	}
+	if opts.printDuplicates {
+		if lineCount > 1 {
+			return true
+		}
+	} else if opts.printEveryOnce || lineCount == 1 {
+		return true
+	}
+
+	return false
+}
+
+func shouldPrintRun(r run, opts options) bool {
+	if r.passthrough {
+		return true
+	}
+	if isEmpty(r.text, opts) {
+		return opts.printEmptyLines
+	}
+	if opts.printDuplicates {
+		return r.count > 1
+	}
+	return opts.printEveryOnce || r.count == 1
+}
+
+func printRun(output io.Writer, jw *jsonWriter, r run, opts options) error {
+	if r.count == 0 || !shouldPrintRun(r, opts) {
+		return nil
+	}
+
+	var nums []int
+	if opts.printLineNumber || jw != nil {
+		nums = make([]int, r.count)
+		for i := range nums {
+			nums[i] = r.first + i
+		}
+	}
+	if jw != nil {
+		return jw.write(lineJSON{recordText(r.text, opts), r.count, cappedNums(nums, opts)})
+	}
+
+	var buf bytes.Buffer
+	if opts.printCount {
+		fmt.Fprintf(&buf, "%7d ", r.count)
+	}
+	if opts.printLineNumber {
+		writeNums(&buf, nums, opts)
+		buf.WriteString(": ")
+	}
+	buf.WriteString(r.text)
+
+	_, err := output.Write(buf.Bytes())
+	return err
+}
+
+// scanAdjacent collapses only adjacent duplicated lines, like POSIX
+// uniq, writing every run as soon as it ends. Memory use doesn't grow
+// with the input, so it works on endless streams.
+func scanAdjacent(input io.Reader, output io.Writer, opts options) error {
+	reader := bufio.NewReader(input)
+	jw := newJSONWriter(output, opts)
+	var cur run
+
+	lineNum := 0
+	for {
+		lineNum++
+		lineStr, err := readRecord(reader, opts)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key, matched := lineKey(lineStr, opts)
+		if !matched && opts.noMatch == noMatchSkip {
+			// the skipped record still parts the lines around it
+			if err := printRun(output, jw, cur, opts); err != nil {
+				return err
+			}
+			cur = run{}
+			continue
+		}
+		if cur.count > 0 && key == cur.key && matched == cur.matched && !cur.passthrough {
+			cur.count++
+			continue
+		}
+		if err := printRun(output, jw, cur, opts); err != nil {
+			return err
+		}
+		cur = run{
+			key:         key,
+			matched:     matched,
+			passthrough: !matched && opts.noMatch == noMatchPass,
+			text:        lineStr,
+			first:       lineNum,
+			count:       1,
+		}
+	}
+
+	if err := printRun(output, jw, cur, opts); err != nil {
+		return err
+	}
+	if jw != nil {
+		return jw.end()
+	}
+	return nil
+}
+
+func uniq(output io.Writer, linesp []*Line, opts options) error {
+	var buf bytes.Buffer
+	jw := newJSONWriter(output, opts)
+
+	for _, linep := range linesp {
+		if !shouldPrint(linep, opts) {
+			continue
+		}
+		text, err := linep.content()
+		if err != nil {
+			return err
+		}
+
+		if jw != nil {
+			err := jw.write(lineJSON{recordText(text, opts), len(linep.nums), cappedNums(linep.nums, opts)})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		buf.Reset()
+		if opts.printCount {
+			fmt.Fprintf(&buf, "%7d ", len(linep.nums))
+		}
+		if opts.printLineNumber {
+			writeNums(&buf, linep.nums, opts)
+			buf.WriteString(": ")
+		}
+		buf.WriteString(text)
+
+		if _, err := output.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if jw != nil {
+		return jw.end()
+	}
+	return nil
+}
+
+// scanInputs reads the named inputs into set, in parallel when they are
+// seekable and -j asks for it.
+func scanInputs(set *lineSet, names []string, stdin io.Reader) error {
+	opts := set.opts
+	if opts.jobs > 1 && opts.memLimit == 0 && seekable(names) {
+		return set.scanParallel(names)
+	}
+
+	input, closeInputs, err := openInputs(names, opts.delimiter(), stdin)
+	if err != nil {
+		return err
+	}
+	defer closeInputs()
+	return set.scan(input)
+}
+
+func process(opts options, stdin io.Reader, stdout io.Writer) error {
+	output := stdout
+	if opts.output != "" && opts.output != "-" {
+		file, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	if opts.adjacentOnly {
+		input, closeInputs, err := openInputs(opts.inputs, opts.delimiter(), stdin)
+		if err != nil {
+			return err
+		}
+		defer closeInputs()
+		return scanAdjacent(input, output, opts)
+	}
+
+	if opts.distinct || opts.heavy > 0 {
+		input, closeInputs, err := openInputs(opts.inputs, opts.delimiter(), stdin)
+		if err != nil {
+			return err
+		}
+		defer closeInputs()
+		return estimate(input, output, opts)
+	}
+
+	if opts.setOp != "" {
+		sets := make([]*lineSet, len(opts.inputs))
+		for i, name := range opts.inputs {
+			sets[i] = newLineSet(opts)
+			defer sets[i].Close()
+			if err := scanInputs(sets[i], []string{name}, stdin); err != nil {
+				return err
+			}
+		}
+		lines, err := compareSets(sets, opts.setOp)
+		if err != nil {
+			return err
+		}
+
+		buffered := bufio.NewWriter(output)
+		if err := printSets(buffered, lines, opts); err != nil {
+			return err
+		}
+		return buffered.Flush()
+	}
+
+	set := newLineSet(opts)
+	defer set.Close()
+	if err := scanInputs(set, opts.inputs, stdin); err != nil {
+		return err
+	}
+
+	linesp := set.lines
+	if opts.sortByCount || opts.top > 0 {
+		linesp = rankLines(linesp, opts)
+	}
+
+	buffered := bufio.NewWriter(output)
+	if err := uniq(buffered, linesp, opts); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// Run runs uniq with args, args[0] being the command name, returning
+// the exit status. env may turn on verbose diagnostics with ENZO_DEBUG.
+func Run(stdin io.Reader, stdout, stderr io.Writer, args, env []string) int {
+	opts, flags, err := parseCommandLine(args)
+	if err != nil {
+		return flags.Report(err, stdout, stderr)
+	}
+	if err := process(opts, stdin, stdout); err != nil {
+		flags.Diag(stderr, env).Error("", err)
+		return 1
+	}
+	return 0
+}
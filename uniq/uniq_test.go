@@ -0,0 +1,1012 @@
+package uniq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var input = []byte(`hello
+world
+
+hello
+
+世界
+世界
+世
+1
+3
+4
+日本語
+4
+1
+`)
+
+func newLine(s string, ln []int) *Line {
+	str := s + "\n"
+	return &Line{
+		text: &str,
+		nums: ln,
+	}
+}
+
+var ttScanLines = []struct {
+	opts     options
+	expected []*Line
+}{
+	{
+		options{printEmptyLines: false},
+		[]*Line{
+			newLine("hello", []int{1, 4}),
+			newLine("world", []int{2}),
+			newLine("世界", []int{6, 7}),
+			newLine("世", []int{8}),
+			newLine("1", []int{9, 14}),
+			newLine("3", []int{10}),
+			newLine("4", []int{11, 13}),
+			newLine("日本語", []int{12}),
+		},
+	},
+	{
+		options{printEmptyLines: true},
+		[]*Line{
+			newLine("hello", []int{1, 4}),
+			newLine("world", []int{2}),
+			newLine("", []int{3, 5}),
+			newLine("世界", []int{6, 7}),
+			newLine("世", []int{8}),
+			newLine("1", []int{9, 14}),
+			newLine("3", []int{10}),
+			newLine("4", []int{11, 13}),
+			newLine("日本語", []int{12}),
+		},
+	},
+}
+
+var ttUniq = []struct {
+	opts     options
+	lines    []*Line
+	expected string
+}{
+	{
+		// uniq
+		options{},
+		ttScanLines[0].expected,
+		`world
+世
+3
+日本語
+`,
+	},
+	{
+		// uniq -num
+		options{printLineNumber: true},
+		ttScanLines[0].expected,
+		`2: world
+8: 世
+10: 3
+12: 日本語
+`,
+	},
+	{
+		// uniq -empty
+		options{printEmptyLines: true},
+		ttScanLines[1].expected,
+		`world
+
+世
+3
+日本語
+`,
+	},
+	{
+		// uniq -empty -num
+		options{
+			printEmptyLines: true,
+			printLineNumber: true,
+		},
+		ttScanLines[1].expected,
+		"2: world\n" +
+			"3,5: \n" +
+			`8: 世
+10: 3
+12: 日本語
+`,
+	},
+	{
+		// uniq -dup
+		options{printDuplicates: true},
+		ttScanLines[0].expected,
+		`hello
+世界
+1
+4
+`,
+	},
+	{
+		// uniq -dup -num
+		options{
+			printDuplicates: true,
+			printLineNumber: true,
+		},
+		ttScanLines[0].expected,
+		`1,4: hello
+6,7: 世界
+9,14: 1
+11,13: 4
+`,
+	},
+	{
+		// uniq -dup -empty
+		options{
+			printDuplicates: true,
+			printEmptyLines: true,
+		},
+		ttScanLines[1].expected,
+		`hello
+
+世界
+1
+4
+`,
+	},
+	{
+		// uniq -dup -empty -num
+		options{
+			printDuplicates: true,
+			printEmptyLines: true,
+			printLineNumber: true,
+		},
+		ttScanLines[1].expected,
+		"1,4: hello\n" +
+			"3,5: \n" +
+			`6,7: 世界
+9,14: 1
+11,13: 4
+`,
+	},
+
+	{
+		// uniq -every
+		options{printEveryOnce: true},
+		ttScanLines[0].expected,
+		`hello
+world
+世界
+世
+1
+3
+4
+日本語
+`,
+	},
+	{
+		// uniq -every -num
+		options{
+			printEveryOnce:  true,
+			printLineNumber: true,
+		},
+		ttScanLines[0].expected,
+		`1,4: hello
+2: world
+6,7: 世界
+8: 世
+9,14: 1
+10: 3
+11,13: 4
+12: 日本語
+`,
+	},
+	{
+		// uniq -every -empty
+		options{
+			printEveryOnce:  true,
+			printEmptyLines: true,
+		},
+		ttScanLines[1].expected,
+		`hello
+world
+
+世界
+世
+1
+3
+4
+日本語
+`,
+	},
+	{
+		// uniq -every -empty -num
+		options{
+			printEveryOnce:  true,
+			printEmptyLines: true,
+			printLineNumber: true,
+		},
+		ttScanLines[1].expected,
+		"1,4: hello\n" +
+			"2: world\n" +
+			"3,5: \n" +
+			`6,7: 世界
+8: 世
+9,14: 1
+10: 3
+11,13: 4
+12: 日本語
+`,
+	},
+}
+
+func cmpLines(a, b []*Line) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("%T sizes diverge: %v != %v", a, len(a), len(b))
+	}
+	for i, _ := range a {
+		linea := a[i]
+		lineb := b[i]
+		if *linea.text != *lineb.text {
+			return fmt.Errorf("line %d:\n%v !=\n%v",
+				i+1, *linea.text, *lineb.text)
+		}
+
+		if lena, lenb := len(linea.nums), len(lineb.nums); lena != lenb {
+			return fmt.Errorf("line counts diverge: %v != %v", lena, lenb)
+		}
+		for n, _ := range linea.nums {
+			lna := linea.nums[n]
+			lnb := lineb.nums[n]
+			if lna != lnb {
+				return fmt.Errorf("line number index: %d -"+
+					"numbers diverge: %v != %v", n, lna, lnb)
+			}
+		}
+	}
+	return nil
+}
+
+func TestScanLines(t *testing.T) {
+	for i, tScanLines := range ttScanLines {
+		bytesReader := bytes.NewReader(input)
+		scannedLines, err := scanLines(bytesReader, tScanLines.opts)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if scannedLines == nil {
+			t.Fatalf("test index %d: %v %[2]T %[2]v", i, scannedLines)
+		}
+
+		err = cmpLines(scannedLines, tScanLines.expected)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+	}
+}
+
+func getUniqOutput(lines []*Line, opts options) (string, error) {
+	var buf bytes.Buffer
+
+	if err := uniq(&buf, lines, opts); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestUniq(t *testing.T) {
+	for i, tUniq := range ttUniq {
+		output, err := getUniqOutput(tUniq.lines, tUniq.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != tUniq.expected {
+			t.Fatalf("test index: %d\nexpected:\n%v\noutput:\n%v",
+				i, tUniq.expected, output)
+		}
+	}
+}
+
+var adjacentInput = []byte(`a
+a
+b
+a
+
+
+c
+c
+c
+`)
+
+var ttScanAdjacent = []struct {
+	opts     options
+	expected string
+}{
+	{
+		// uniq -adj
+		options{adjacentOnly: true},
+		"b\na\n",
+	},
+	{
+		// uniq -adj -every
+		options{adjacentOnly: true, printEveryOnce: true},
+		"a\nb\na\nc\n",
+	},
+	{
+		// uniq -adj -every -empty -num
+		options{
+			adjacentOnly:    true,
+			printEveryOnce:  true,
+			printEmptyLines: true,
+			printLineNumber: true,
+		},
+		"1,2: a\n3: b\n4: a\n5,6: \n7,8,9: c\n",
+	},
+	{
+		// uniq -adj -dup -num
+		options{
+			adjacentOnly:    true,
+			printDuplicates: true,
+			printLineNumber: true,
+		},
+		"1,2: a\n7,8,9: c\n",
+	},
+}
+
+func TestScanAdjacent(t *testing.T) {
+	for i, test := range ttScanAdjacent {
+		var buf bytes.Buffer
+		err := scanAdjacent(bytes.NewReader(adjacentInput), &buf, test.opts)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if output := buf.String(); output != test.expected {
+			t.Fatalf("test index: %d\nexpected:\n%v\noutput:\n%v",
+				i, test.expected, output)
+		}
+	}
+}
+
+// chanWriter sends every write on a channel, to check output isn't
+// held back until EOF.
+type chanWriter chan string
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- string(p)
+	return len(p), nil
+}
+
+func TestScanAdjacentStreams(t *testing.T) {
+	reader, writer := io.Pipe()
+	output := make(chanWriter, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- scanAdjacent(reader, output, options{printEveryOnce: true})
+	}()
+
+	io.WriteString(writer, "a\na\nb\n")
+	io.WriteString(writer, "c\n")
+
+	for _, expected := range []string{"a\n", "b\n"} {
+		select {
+		case got := <-output:
+			if got != expected {
+				t.Fatalf("got %q, expected %q", got, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q not written before EOF", expected)
+		}
+	}
+
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := <-output; got != "c\n" {
+		t.Fatalf("got %q at EOF", got)
+	}
+}
+
+func lineTexts(lines []*Line) string {
+	var buf bytes.Buffer
+	for _, linep := range lines {
+		buf.WriteString(*linep.text)
+	}
+	return buf.String()
+}
+
+func TestRankLines(t *testing.T) {
+	tests := []struct {
+		opts     options
+		expected string
+	}{
+		{
+			options{printEveryOnce: true, sortByCount: true},
+			"hello\n世界\n1\n4\nworld\n世\n3\n日本語\n",
+		},
+		{
+			options{printEveryOnce: true, sortByCount: true, ascending: true},
+			"world\n世\n3\n日本語\nhello\n世界\n1\n4\n",
+		},
+		{
+			options{printEveryOnce: true, top: 3},
+			"hello\n世界\n1\n",
+		},
+		{
+			options{printEveryOnce: true, sortByCount: true, ascending: true, top: 5},
+			"world\n世\n3\n日本語\nhello\n",
+		},
+		{
+			options{printDuplicates: true, top: 100},
+			"hello\n世界\n1\n4\n",
+		},
+	}
+
+	for i, test := range tests {
+		ranked := rankLines(ttScanLines[0].expected, test.opts)
+		if output := lineTexts(ranked); output != test.expected {
+			t.Errorf("test index: %d\nexpected:\n%v\noutput:\n%v",
+				i, test.expected, output)
+		}
+	}
+}
+
+func TestPrintCounts(t *testing.T) {
+	opts := options{printEveryOnce: true, printCount: true, top: 2}
+	output, err := getUniqOutput(rankLines(ttScanLines[0].expected, opts), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "      2 hello\n      2 世界\n"
+	if output != expected {
+		t.Fatalf("expected:\n%v\noutput:\n%v", expected, output)
+	}
+
+	var buf bytes.Buffer
+	opts = options{printEveryOnce: true, printCount: true}
+	if err := scanAdjacent(bytes.NewReader(adjacentInput), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected = "      2 a\n      1 b\n      1 a\n      3 c\n"
+	if output := buf.String(); output != expected {
+		t.Fatalf("expected:\n%v\noutput:\n%v", expected, output)
+	}
+}
+
+func TestParseTopArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"uniq", "-every", "-top", "10", "-count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.top != 10 || !opts.printCount || !opts.printEveryOnce {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	// ranking implies -every, unless -dup is given
+	for _, test := range []struct {
+		args    []string
+		every   bool
+		dupOnly bool
+	}{
+		{[]string{"uniq", "-top", "2"}, true, false},
+		{[]string{"uniq", "-freq"}, true, false},
+		{[]string{"uniq", "-dup", "-freq-asc"}, false, true},
+		{[]string{"uniq", "-count"}, false, false},
+	} {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.printEveryOnce != test.every || opts.printDuplicates != test.dupOnly {
+			t.Errorf("%v: unexpected options: %+v", test.args, opts)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	status := Run(strings.NewReader("a\nb\na\nc\na\nc\n"), &stdout, &stderr, []string{"uniq", "-top", "2", "-count"}, nil)
+	if expected := "      3 a\n      2 c\n"; status != 0 || stdout.String() != expected {
+		t.Errorf("-top 2: status %d, expected %q, got %q", status, expected, stdout.String())
+	}
+
+	for _, args := range [][]string{
+		{"uniq", "-top"},
+		{"uniq", "-top", "ten"},
+		{"uniq", "-adj", "-freq"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestLineKey(t *testing.T) {
+	tests := []struct {
+		line     string
+		opts     options
+		expected string
+	}{
+		{"a b c\n", options{}, "a b c\n"},
+		{"2017-01-02 10:00:01 disk full\n", options{skipFields: 2}, " disk full"},
+		{"  one\ttwo three\n", options{skipFields: 1}, "\ttwo three"},
+		{"one two\n", options{skipFields: 5}, ""},
+		{"[001] 世界 ok\n", options{skipChars: 6}, "世界 ok"},
+		{"x y 世界 ok\n", options{skipFields: 1, skipChars: 1, checkChars: 3}, "y 世"},
+		{"Straße kelvin\n", options{foldCase: true}, "STRAßE KELVIN"},
+		{"\u212a\n", options{foldCase: true}, "K"},
+		{"trailing \t \n", options{trimTrailing: true}, "trailing"},
+	}
+
+	for _, test := range tests {
+		if key, _ := lineKey(test.line, test.opts); key != test.expected {
+			t.Errorf("%q %+v: got %q, expected %q", test.line, test.opts, key, test.expected)
+		}
+	}
+}
+
+var keyedInput = []byte(`10:00:01 ERROR disk full
+10:00:02 error Disk Full  
+10:00:03 INFO started
+10:00:04 ERROR disk full
+`)
+
+func TestScanLinesByKey(t *testing.T) {
+	opts := options{skipFields: 1, foldCase: true, trimTrailing: true}
+	lines, err := scanLines(bytes.NewReader(keyedInput), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Line{
+		newLine("10:00:01 ERROR disk full", []int{1, 2, 4}),
+		newLine("10:00:03 INFO started", []int{3}),
+	}
+	if err := cmpLines(lines, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	opts.adjacentOnly = true
+	opts.printEveryOnce = true
+	opts.printLineNumber = true
+	if err := scanAdjacent(bytes.NewReader(keyedInput), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	expectedAdj := "1,2: 10:00:01 ERROR disk full\n" +
+		"3: 10:00:03 INFO started\n" +
+		"4: 10:00:04 ERROR disk full\n"
+	if output := buf.String(); output != expectedAdj {
+		t.Fatalf("expected:\n%v\noutput:\n%v", expectedAdj, output)
+	}
+}
+
+var logInput = []byte(`GET /health 200
+GET /users 500
+starting
+GET /health 200
+POST /users 201
+GET /users 503
+stopping
+`)
+
+func TestScanLinesByPattern(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"uniq", "-every", "-num", "-re", `^\S+ (\S+)`},
+			"1,4: GET /health 200\n" +
+				"2,5,6: GET /users 500\n" +
+				"3: starting\n" +
+				"7: stopping\n",
+		},
+		{
+			[]string{"uniq", "-dup", "-num", "-re", `(?P<code>\d)\d\d$`,
+				"-group", "code", "-nomatch", "skip"},
+			"1,4,5: GET /health 200\n" +
+				"2,6: GET /users 500\n",
+		},
+		{
+			[]string{"uniq", "-every", "-count", "-re", `\d+$`, "-nomatch", "count"},
+			"      2 GET /health 200\n" +
+				"      1 GET /users 500\n" +
+				"      2 starting\n" +
+				"      1 POST /users 201\n" +
+				"      1 GET /users 503\n",
+		},
+		{
+			[]string{"uniq", "-dup", "-re", `^(GET|POST) (/\w+)`, "-group", "2"},
+			"GET /health 200\n" +
+				"GET /users 500\n" +
+				"starting\n" +
+				"stopping\n",
+		},
+	}
+
+	for i, test := range tests {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		lines, err := scanLines(bytes.NewReader(logInput), opts)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		output, err := getUniqOutput(lines, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != test.expected {
+			t.Errorf("test index: %d\nexpected:\n%v\noutput:\n%v",
+				i, test.expected, output)
+		}
+	}
+}
+
+func TestScanAdjacentByPattern(t *testing.T) {
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{
+			[]string{"-re", `^\S+ (\S+)`, "-nomatch", "count"},
+			"GET /a 1\nGET /a 2\nx\ny\nGET /a 3\n",
+			"1,2: GET /a 1\n3,4: x\n5: GET /a 3\n",
+		},
+		{
+			[]string{"-re", `[a-z] ([0-9])`, "-nomatch", "skip"},
+			"a 1\nzzz\na 1\n",
+			"1: a 1\n3: a 1\n",
+		},
+		{
+			[]string{"-re", `[a-z] ([0-9])`, "-nomatch", "skip", "-ndjson"},
+			"a 1\nzzz\na 1\n",
+			`{"text":"a 1","count":1,"lines":[1]}` + "\n" +
+				`{"text":"a 1","count":1,"lines":[3]}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		opts, err := parseArgs(append([]string{"uniq", "-adj", "-every", "-num"}, test.args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := scanAdjacent(strings.NewReader(test.input), &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		if output := buf.String(); output != test.expected {
+			t.Errorf("%v: expected:\n%v\noutput:\n%v", test.args, test.expected, output)
+		}
+	}
+}
+
+func TestParsePatternArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"uniq", "-re", "("},
+		{"uniq", "-re", "(a)", "-group", "2"},
+		{"uniq", "-re", "(a)", "-group", "name"},
+		{"uniq", "-re", "a", "-nomatch", "drop"},
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestRecordDelimiters(t *testing.T) {
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{
+			[]string{"uniq", "-every", "-num"},
+			"a\nb\na",
+			"1,3: a\n2: b\n",
+		},
+		{
+			[]string{"uniq"},
+			"a\nb\nb",
+			"a\n",
+		},
+		{
+			[]string{"uniq", "-adj", "-every", "-num"},
+			"a\nb\nb",
+			"1: a\n2,3: b\n",
+		},
+		{
+			[]string{"uniq", "-every", "-z"},
+			"./a\x00./b\n\x00./a\x00./b\n",
+			"./a\x00./b\n\x00",
+		},
+		{
+			[]string{"uniq", "-every", "-empty", "-num", "-delim", ","},
+			"x,y,,x,,",
+			"1,4: x,2: y,3,5: ,",
+		},
+		{
+			[]string{"uniq", "-dup", "--delim", `\x00`},
+			"a\x00a",
+			"a\x00",
+		},
+		{
+			// -d and -c as in POSIX uniq
+			[]string{"uniq", "-dc"},
+			"a\na\nb\n",
+			"      2 a\n",
+		},
+		{
+			[]string{"uniq", "-every", "-num", "-crlf"},
+			"a\r\nb\na\n\r\nb\r\n",
+			"1,3: a\r\n2,5: b\n",
+		},
+		{
+			[]string{"uniq", "-every", "-empty", "-num", "-crlf"},
+			"a\r\n\r\n\na",
+			"1,4: a\r\n2,3: \r\n",
+		},
+	}
+
+	for i, test := range tests {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+
+		var output string
+		if opts.adjacentOnly {
+			var buf bytes.Buffer
+			err = scanAdjacent(strings.NewReader(test.input), &buf, opts)
+			output = buf.String()
+		} else {
+			var lines []*Line
+			lines, err = scanLines(strings.NewReader(test.input), opts)
+			if err == nil {
+				output, err = getUniqOutput(lines, opts)
+			}
+		}
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if output != test.expected {
+			t.Errorf("test index: %d\nexpected:\n%q\noutput:\n%q",
+				i, test.expected, output)
+		}
+	}
+}
+
+func TestParseOperands(t *testing.T) {
+	tests := []struct {
+		args   []string
+		inputs []string
+		output string
+	}{
+		{[]string{"uniq", "-every"}, nil, ""},
+		{[]string{"uniq", "in.txt"}, []string{"in.txt"}, ""},
+		{[]string{"uniq", "-", "out.txt"}, []string{"-"}, "out.txt"},
+		{[]string{"uniq", "-merge", "a", "b", "c"}, []string{"a", "b", "c"}, ""},
+	}
+
+	for i, test := range tests {
+		opts, err := parseArgs(test.args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if fmt.Sprint(opts.inputs) != fmt.Sprint(test.inputs) ||
+			opts.output != test.output {
+			t.Errorf("test index %d: unexpected operands %v %q",
+				i, opts.inputs, opts.output)
+		}
+	}
+
+	if _, err := parseArgs([]string{"uniq", "a", "b", "c"}); err == nil {
+		t.Error("expected an error for three operands without -merge")
+	}
+}
+
+func TestMergedInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uniq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var names []string
+	for i, content := range []string{"a\nb", "b\nc\n", "", "a"} {
+		name := filepath.Join(dir, fmt.Sprintf("in%d", i))
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	opts := options{printEveryOnce: true, printLineNumber: true}
+	input, closeInputs, err := openInputs(names, '\n', nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs()
+
+	lines, err := scanLines(input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := getUniqOutput(lines, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1,5: a\n2,3: b\n4: c\n"
+	if output != expected {
+		t.Errorf("expected:\n%q\noutput:\n%q", expected, output)
+	}
+
+	if _, _, err := openInputs([]string{filepath.Join(dir, "missing")}, '\n', nil); err == nil {
+		t.Error("expected an error opening a missing input")
+	}
+}
+
+func TestTerminatedReaderSmallReads(t *testing.T) {
+	r := &terminatedReader{r: strings.NewReader("ab"), delim: '\n'}
+	var out []byte
+	p := make([]byte, 1)
+	for {
+		n, err := r.Read(p)
+		out = append(out, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out) != "ab\n" {
+		t.Errorf("expected %q, got %q", "ab\n", out)
+	}
+}
+
+func TestSpilledLines(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&input, "a fairly long line to spill number %d\n", i%37)
+		if i%11 == 0 {
+			input.WriteString("\nshort\n")
+		}
+		if i%13 == 0 {
+			input.WriteString("unmatched line without any digits in it\n")
+		}
+	}
+
+	for i, args := range [][]string{
+		{"uniq"},
+		{"uniq", "-every", "-num", "-empty"},
+		{"uniq", "-dup", "-count"},
+		{"uniq", "-every", "-freq", "-count"},
+		{"uniq", "-every", "-top", "5", "-num"},
+		{"uniq", "-every", "-num", "-re", `\d+`},
+		{"uniq", "-every", "-count", "-re", `\d+`, "-nomatch", "count"},
+	} {
+		opts, err := parseArgs(args)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+
+		lines, err := scanLines(strings.NewReader(input.String()), opts)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		expected, err := getUniqOutput(rankLines(lines, opts), opts)
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+
+		opts.memLimit = 64
+		set := newLineSet(opts)
+		if err := set.scan(strings.NewReader(input.String())); err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if set.spill == nil {
+			t.Fatalf("test index %d: lines were not spilled", i)
+		}
+		output, err := getUniqOutput(rankLines(set.lines, opts), opts)
+		name := set.spill.file.Name()
+		if closeErr := set.Close(); closeErr != nil {
+			t.Fatalf("test index %d: %v", i, closeErr)
+		}
+		if err != nil {
+			t.Fatalf("test index %d: %v", i, err)
+		}
+		if output != expected {
+			t.Errorf("test index %d: spilled output diverges\nexpected:\n%q\noutput:\n%q",
+				i, expected, output)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("test index %d: spill file %s left behind", i, name)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		val      string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"64K", 64 << 10},
+		{"3M", 3 << 20},
+		{"2G", 2 << 30},
+	}
+
+	for _, test := range tests {
+		size, err := parseSize(test.val)
+		if err != nil {
+			t.Fatalf("%s: %v", test.val, err)
+		}
+		if size != test.expected {
+			t.Errorf("%s: expected %d, got %d", test.val, test.expected, size)
+		}
+	}
+
+	for _, val := range []string{"", "K", "-1", "12T", "1.5M"} {
+		if _, err := parseSize(val); err == nil {
+			t.Errorf("%q: expected an error", val)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	status := Run(strings.NewReader("a\nb\na\n"), &stdout, &stderr, []string{"uniq", "-every", "-count"}, nil)
+	if status != 0 || stdout.String() != "      2 a\n      1 b\n" {
+		t.Errorf("status %d, stdout %q", status, stdout.String())
+	}
+
+	stdout.Reset()
+	if status := Run(nil, &stdout, &stderr, []string{"uniq", "-nosuchflag"}, nil); status != 2 {
+		t.Errorf("expected status 2, got %d", status)
+	}
+	if stdout.Len() != 0 || !strings.Contains(stderr.String(), "Usage: uniq ") {
+		t.Errorf("expected the usage on stderr, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	status = Run(strings.NewReader("a\nA\n"), &stdout, &stderr, []string{"uniq", "-iw1", "--every", "--count"}, nil)
+	if status != 0 || stdout.String() != "      2 a\n" {
+		t.Errorf("status %d, stdout %q, stderr %q", status, stdout.String(), stderr.String())
+	}
+}
+
+func TestRunDiagnostics(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	missing := filepath.Join(os.TempDir(), "enzo-uniq-missing")
+
+	status := Run(nil, &stdout, &stderr, []string{"uniq", missing}, nil)
+	if expected := "uniq: " + missing + ": no such file or directory\n"; status != 1 || stderr.String() != expected {
+		t.Errorf("status %d, expected stderr %q, got %q", status, expected, stderr.String())
+	}
+
+	stderr.Reset()
+	status = Run(nil, &stdout, &stderr, []string{"uniq", missing}, []string{"ENZO_DEBUG=1"})
+	if expected := "uniq: " + missing + ": no such file or directory (open, ENOENT)\n"; status != 1 || stderr.String() != expected {
+		t.Errorf("status %d, expected stderr %q, got %q", status, expected, stderr.String())
+	}
+
+	stderr.Reset()
+	status = Run(nil, &stdout, &stderr, []string{"uniq", "-json", "-ndjson"}, nil)
+	if status != 2 || !strings.HasPrefix(stderr.String(), "uniq: choose -json or -ndjson\nUsage: uniq ") {
+		t.Errorf("status %d, stderr %q", status, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing on stdout, got %q", stdout.String())
+	}
+}